@@ -0,0 +1,146 @@
+// Command admin runs one-off maintenance tasks against the database.
+//
+// Usage:
+//
+//	go run ./cmd/admin merge-feeds [-dry-run]
+//	go run ./cmd/admin prune-articles
+//	go run ./cmd/admin set-role -email user@example.com -role admin
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/app"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/config"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/database"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  merge-feeds     normalize feed URLs and merge duplicate feeds")
+	fmt.Fprintln(os.Stderr, "  prune-articles  delete articles outside the retention policy")
+	fmt.Fprintln(os.Stderr, "  set-role        make a user an admin or a regular user")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "merge-feeds":
+		mergeFeeds(os.Args[2:])
+	case "prune-articles":
+		pruneArticles(os.Args[2:])
+	case "set-role":
+		setRole(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// newApp loads the config and wires up the App the same way the API does
+func newApp() (*app.App, *config.Config) {
+	cfg := config.LoadEnv()
+
+	db, err := database.Connect(cfg.DBDSN)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+
+	a, err := app.NewApp(db, cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up the app: %v", err)
+	}
+
+	return a, cfg
+}
+
+func mergeFeeds(args []string) {
+	fs := flag.NewFlagSet("merge-feeds", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report duplicates without changing anything")
+	fs.Parse(args)
+
+	app, _ := newApp()
+
+	report, err := app.FeedService.MergeDuplicateFeeds(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to merge feeds: %v", err)
+	}
+
+	for _, group := range report.Groups {
+		fmt.Printf("%s\n  keep   %s (%s)\n", group.URLKey, group.KeptURL, group.KeptID)
+		for i, id := range group.MergedIDs {
+			fmt.Printf("  merge  %s (%s)\n", group.MergedURLs[i], id)
+		}
+	}
+
+	for _, invalid := range report.InvalidURLs {
+		fmt.Printf("skipped invalid feed url: %s\n", invalid)
+	}
+
+	if *dryRun {
+		fmt.Printf("dry run: %d duplicate groups, %d feeds need their url normalized\n", len(report.Groups), report.KeysUpdated)
+		return
+	}
+
+	fmt.Printf("merged %d duplicate groups, moved %d subscriptions and %d articles, normalized %d feed urls\n",
+		len(report.Groups), report.MovedSubscriptions, report.MovedArticles, report.KeysUpdated)
+}
+
+func pruneArticles(args []string) {
+	fs := flag.NewFlagSet("prune-articles", flag.ExitOnError)
+	fs.Parse(args)
+
+	app, cfg := newApp()
+
+	report, err := app.FeedService.PruneArticles(context.Background(), feeds.RetentionPolicy{
+		MaxAgeDays:  cfg.ArticleRetentionDays,
+		MaxArticles: cfg.ArticleMaxPerFeed,
+		BatchSize:   cfg.ArticlePruneBatch,
+	})
+	if err != nil {
+		log.Fatalf("Failed to prune articles after deleting %d: %v", report.Total(), err)
+	}
+
+	fmt.Printf("pruned %d articles (%d expired, %d over the per-feed limit) in %d batches, took %s\n",
+		report.Total(), report.Expired, report.OverLimit, report.Batches, report.Duration.Round(time.Millisecond))
+}
+
+// setRole changes the role of a user, it also creates the first admin when
+// ADMIN_EMAILS isn't an option
+func setRole(args []string) {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	role := fs.String("role", "admin", "new role, user or admin")
+	fs.Parse(args)
+
+	normalized, err := auth.NormalizeEmail(*email)
+	if err != nil {
+		log.Fatalf("Invalid -email %q", *email)
+	}
+
+	app, _ := newApp()
+
+	user, err := app.UserRepo.GetUserByEmail(normalized)
+	if err != nil {
+		log.Fatalf("Failed to find the user %s: %v", normalized, err)
+	}
+
+	user, err = app.AuthService.SetUserRole(context.Background(), user.ID, *role, auth.ClientInfo{})
+	if err != nil {
+		log.Fatalf("Failed to set the role of %s: %v", normalized, err)
+	}
+
+	fmt.Printf("%s is now %s\n", user.Email, user.Role)
+}
@@ -0,0 +1,319 @@
+// Command mockoidc runs a minimal OpenID Connect provider for trying out single
+// sign-on locally. Every login shows a form to pick the email address to sign in
+// as, nothing is persisted and the signing key changes on every start.
+//
+// Usage:
+//
+//	go run ./cmd/mockoidc [-addr :9000] [-client-id swayamsevak] [-client-secret secret]
+//
+// and point the API at it with
+//
+//	OIDC_ISSUER_URL=http://localhost:9000 OIDC_CLIENT_ID=swayamsevak OIDC_CLIENT_SECRET=secret
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID = "mockoidc"
+
+	codeTTL    = time.Minute
+	idTokenTTL = 5 * time.Minute
+)
+
+// grant is an authorization code waiting to be exchanged
+type grant struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*grant
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match how the API reaches this server")
+	clientID := flag.String("client-id", "swayamsevak", "client ID the API uses")
+	clientSecret := flag.String("client-secret", "secret", "client secret the API uses")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate the signing key: %v", err)
+	}
+
+	p := &provider{
+		issuer:       strings.TrimRight(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        make(map[string]*grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("GET /authorize", p.authorizeForm)
+	mux.HandleFunc("POST /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+
+	log.Printf("Mock OpenID Connect provider %s listening on %s", p.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "email", "email_verified", "name", "preferred_username", "nonce"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock OpenID Connect provider</title>
+<h1>Sign in to {{.ClientID}}</h1>
+<form method="post">
+  {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+  {{end}}
+  <p><label>Email <input name="email" type="email" value="user@example.com" required></label></p>
+  <p><label>Name <input name="name" value="Mock User"></label></p>
+  <p><label><input name="email_verified" type="checkbox" value="true" checked> Email verified</label></p>
+  <p><button name="decision" value="allow">Sign in</button> <button name="decision" value="deny">Deny</button></p>
+</form>
+`))
+
+// authorizeForm checks the authorization request and asks who to sign in as
+func (p *provider) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if err := p.checkAuthorizeRequest(query); err != "" {
+		http.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	params := make(map[string]string)
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method"} {
+		params[name] = query.Get(name)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginForm.Execute(w, map[string]any{
+		"ClientID": p.clientID,
+		"Params":   params,
+	})
+}
+
+// authorize issues an authorization code for the submitted form
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	if err := p.checkAuthorizeRequest(form); err != "" {
+		http.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	redirect, _ := url.Parse(form.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("state", form.Get("state"))
+
+	if form.Get("decision") != "allow" {
+		params.Set("error", "access_denied")
+		params.Set("error_description", "the user denied the request")
+		redirect.RawQuery = params.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+		return
+	}
+
+	code := randomString()
+
+	p.mu.Lock()
+	p.codes[code] = &grant{
+		clientID:      form.Get("client_id"),
+		redirectURI:   form.Get("redirect_uri"),
+		codeChallenge: form.Get("code_challenge"),
+		nonce:         form.Get("nonce"),
+		email:         strings.TrimSpace(form.Get("email")),
+		name:          strings.TrimSpace(form.Get("name")),
+		emailVerified: form.Get("email_verified") == "true",
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	params.Set("code", code)
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// checkAuthorizeRequest returns what is wrong with an authorization request, PKCE is required
+func (p *provider) checkAuthorizeRequest(params url.Values) string {
+	switch {
+	case params.Get("client_id") != p.clientID:
+		return "unknown client_id"
+	case params.Has("response_type") && params.Get("response_type") != "code":
+		return "only the code response type is supported"
+	case params.Get("redirect_uri") == "":
+		return "missing redirect_uri"
+	case params.Get("state") == "":
+		return "missing state"
+	case params.Get("code_challenge") == "" || params.Get("code_challenge_method") != "S256":
+		return "PKCE with S256 is required"
+	}
+
+	if _, err := url.Parse(params.Get("redirect_uri")); err != nil {
+		return "invalid redirect_uri"
+	}
+	return ""
+}
+
+// token exchanges an authorization code for an ID token
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.clientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single use, even when the exchange fails
+	p.mu.Lock()
+	g, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !found || time.Now().After(g.expiresAt) || g.clientID != clientID || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	idToken, err := p.signIDToken(g)
+	if err != nil {
+		log.Printf("Failed to sign the id token: %v", err)
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// signIDToken creates the ID token of a grant. The subject is derived from the
+// email, so signing in with the same address always gives the same account.
+func (p *provider) signIDToken(g *grant) (string, error) {
+	sub := sha256.Sum256([]byte(strings.ToLower(g.email)))
+	username, _, _ := strings.Cut(g.email, "@")
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                hex.EncodeToString(sub[:16]),
+		"aud":                g.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTokenTTL).Unix(),
+		"email":              g.email,
+		"email_verified":     g.emailVerified,
+		"name":               g.name,
+		"preferred_username": username,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+
+	return token.SignedString(p.key)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
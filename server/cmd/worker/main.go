@@ -0,0 +1,20 @@
+// Command worker runs the background work (feed fetch scheduler) without the
+// HTTP API. It is equivalent to `api --role=worker` and lets fetching be scaled
+// independently from serving.
+package main
+
+import (
+	"log"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/config"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/server"
+)
+
+func main() {
+	// Load config
+	cfg := config.LoadEnv()
+
+	if err := server.Run(cfg, server.RoleWorker); err != nil {
+		log.Fatal(err)
+	}
+}
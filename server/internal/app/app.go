@@ -2,11 +2,18 @@ package app
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/config"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/opml"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/secretbox"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/stream"
 )
 
 type App struct {
@@ -15,31 +22,114 @@ type App struct {
 
 	AuthService *auth.AuthService
 
-	FeedRepo    *models.FeedRepository
-	FeedService *feeds.FeedService
+	FeedRepo       *models.FeedRepository
+	FeedService    *feeds.FeedService
+	RefreshService *feeds.RefreshService
 
 	ArticleRepo *models.ArticleRepository
+
+	OPMLService *opml.OPMLService
+
+	JobRepo    *models.JobRepository
+	JobService *jobs.JobService
+
+	Broker        *stream.Broker
+	StreamService *stream.StreamService
 }
 
-func NewApp(db *sql.DB, jwtSecret string, accessTokenTTL time.Duration) *App {
+func NewApp(db *sql.DB, cfg *config.Config) (*App, error) {
+	// Background jobs, every process registers the same handlers
+	jobRepo := models.NewJobRepository(db)
+	jobService := jobs.NewJobService(jobRepo)
+	jobService.RegisterCleanup(time.Hour, 7*24*time.Hour)
+
+	// Emails are queued encrypted and delivered by the workers
+	mailer, err := mail.New(mail.Config{
+		Driver:       cfg.MailDriver,
+		From:         cfg.MailFrom,
+		Development:  cfg.Environment == "development",
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUsername: cfg.SMTPUsername,
+		SMTPPassword: cfg.SMTPPassword,
+		Dir:          cfg.MailDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configuring mail: %w", err)
+	}
+	mailBox, err := secretbox.New(cfg.SecretKey, []byte(cfg.JWTSecret))
+	if err != nil {
+		return nil, fmt.Errorf("configuring mail: %w", err)
+	}
+	mail.RegisterDelivery(jobService, mailer, mailBox)
+
 	userRepo := models.NewUserRepository(db)
 	refreshRepo := models.NewRefreshTokenRepository(db)
-	authService := auth.NewAuthService(userRepo, refreshRepo, jwtSecret, accessTokenTTL)
+	sessionRepo := models.NewSessionRepository(db)
+	securityRepo := models.NewSecurityEventRepository(db)
+	userTokenRepo := models.NewUserTokenRepository(db)
+	mfaRepo := models.NewMFARepository(db)
+	identityRepo := models.NewIdentityRepository(db)
+	apiTokenRepo := models.NewAPITokenRepository(db)
+	rateLimitRepo := models.NewRateLimitRepository(db)
+	authService, err := auth.NewAuthService(userRepo, refreshRepo, sessionRepo, securityRepo, userTokenRepo, mfaRepo, identityRepo, apiTokenRepo, rateLimitRepo, mail.NewQueuedMailer(jobService, mailBox), jobService, auth.Config{
+		JWTSecret:            cfg.JWTSecret,
+		AccessTokenTTL:       cfg.AccessTokenTTL,
+		AppBaseURL:           cfg.AppBaseURL,
+		EmailVerificationTTL: cfg.EmailVerificationTTL,
+		PasswordResetTTL:     cfg.PasswordResetTTL,
+		SecretKey:            cfg.SecretKey,
+		OIDC: auth.OIDCConfig{
+			IssuerURL:     cfg.OIDCIssuerURL,
+			ClientID:      cfg.OIDCClientID,
+			ClientSecret:  cfg.OIDCClientSecret,
+			RedirectURL:   cfg.OIDCRedirectURL,
+			Scopes:        cfg.OIDCScopes,
+			AutoProvision: cfg.OIDCAutoProvision,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configuring auth: %w", err)
+	}
 
 	feedRepo := models.NewFeedRepository(db)
 	feedSubscriptionRepo := models.NewFeedSubscriptionRepository(db)
 	articleRepo := models.NewArticleRepository(db)
 
-	fetcher := feeds.NewFetcher()
+	fetcher := feeds.NewFetcher(cfg.FetchTimeout)
 
 	feedService := feeds.NewFeedService(feedRepo, feedSubscriptionRepo, articleRepo, fetcher)
 
+	refreshService := feeds.NewRefreshService(feedService, jobService)
+
+	opmlService := opml.NewOPMLService(feedService, jobService)
+
+	feedService.RegisterPruneJob(jobService, cfg.ArticlePruneInterval, feeds.RetentionPolicy{
+		MaxAgeDays:  cfg.ArticleRetentionDays,
+		MaxArticles: cfg.ArticleMaxPerFeed,
+		BatchSize:   cfg.ArticlePruneBatch,
+	})
+	authService.RegisterTokenCleanup(jobService, cfg.RefreshTokenCleanupInterval, cfg.RefreshTokenCleanupBatch)
+	if cfg.OrphanFeedGrace > 0 {
+		feedService.RegisterOrphanCollector(jobService, cfg.OrphanFeedGCInterval, cfg.OrphanFeedGrace)
+	}
+
+	// Article streams, woken by Postgres notifications from any instance
+	broker := stream.NewBroker(cfg.DBDSN)
+	streamService := stream.NewStreamService(articleRepo, feedSubscriptionRepo, broker)
+
 	return &App{
 		UserRepo:         userRepo,
 		RefreshTokenRepo: refreshRepo,
 		AuthService:      authService,
 		FeedRepo:         feedRepo,
 		FeedService:      feedService,
+		RefreshService:   refreshService,
 		ArticleRepo:      articleRepo,
-	}
+		OPMLService:      opmlService,
+		JobRepo:          jobRepo,
+		JobService:       jobService,
+		Broker:           broker,
+		StreamService:    streamService,
+	}, nil
 }
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidUsername = errors.New("invalid username")
+	ErrUsernameInUse   = errors.New("username already in use")
+)
+
+const maxUsernameLength = 64
+
+// UpdateProfile changes the user's username
+func (s *AuthService) UpdateProfile(ctx context.Context, userID uuid.UUID, username string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || utf8.RuneCountInString(username) > maxUsernameLength {
+		return nil, ErrInvalidUsername
+	}
+
+	if err := s.userRepo.UpdateUsername(ctx, userID, username); err != nil {
+		if errors.Is(err, models.ErrUsernameTaken) {
+			return nil, ErrUsernameInUse
+		}
+		return nil, err
+	}
+
+	return s.userRepo.GetUserByID(userID)
+}
+
+// ChangePassword replaces the user's password after checking the current one.
+// Every other session is signed out and personal access tokens are deleted, the
+// session making the change keeps working. It returns how many sessions were
+// signed out.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, currentPassword, newPassword string, client ClientInfo) (int64, error) {
+	user, err := s.reauthenticate(userID, currentPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ValidatePassword(newPassword); err != nil {
+		return 0, err
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	// All or nothing, so old sessions and personal access tokens, which may have
+	// been created by whoever knew the old password, never outlive it. Access
+	// tokens without a session keep nothing, uuid.Nil matches no session.
+	revoked, err := s.userRepo.ChangePassword(ctx, user.ID, currentSessionID, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	s.recordSecurityEvent(ctx, user.ID, models.SecurityEventPasswordChanged, currentSessionID, client)
+	s.notify(ctx, user.Email, "Your password was changed",
+		"Hi "+user.Username+",\n\n"+
+			"The password of your account was just changed, your other devices were signed out and your personal access tokens were deleted.\n\n"+
+			"If this wasn't you, reset your password right away.\n")
+
+	return revoked, nil
+}
+
+// ChangeEmail requests moving the account to a new email address after
+// checking the password. The new address is stored as pending and only
+// replaces the current one once the link emailed to it is followed, so the
+// account keeps its verified address until then. The current address is told
+// about the request.
+func (s *AuthService) ChangeEmail(ctx context.Context, userID, currentSessionID uuid.UUID, password, newEmail string, client ClientInfo) (*models.User, error) {
+	user, err := s.reauthenticate(userID, password)
+	if err != nil {
+		return nil, err
+	}
+
+	newEmail, err = NormalizeEmail(newEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	if newEmail == user.Email {
+		return user, nil
+	}
+
+	if err := s.userRepo.SetPendingEmail(ctx, user.ID, newEmail); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			return nil, ErrEmailInUse
+		}
+		return nil, err
+	}
+
+	user, err = s.userRepo.GetUserByID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordSecurityEvent(ctx, user.ID, models.SecurityEventEmailChanged, currentSessionID, client)
+
+	if err := s.sendVerificationEmail(ctx, user, newEmail); err != nil {
+		log.Printf("change email: failed to send verification email to %s: %v", newEmail, err)
+	}
+	s.notify(ctx, user.Email, "Your email address is being changed",
+		"Hi "+user.Username+",\n\n"+
+			"Someone asked to move your account to "+newEmail+". The change takes effect once the link sent there is opened.\n\n"+
+			"If this wasn't you, change your password right away.\n")
+
+	return user, nil
+}
+
+// DeleteAccount deletes the user after checking their password
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uuid.UUID, password string) error {
+	user, err := s.reauthenticate(userID, password)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.DeleteUser(ctx, user.ID)
+}
+
+// reauthenticate loads the user and checks their password before a sensitive
+// change. Users without a password get ErrPasswordNotSet, they set one through
+// a password reset first, which proves they own the email address.
+func (s *AuthService) reauthenticate(userID uuid.UUID, password string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.HasPassword() {
+		return nil, ErrPasswordNotSet
+	}
+	if err := VerifyPassword(user.PasswordHash, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// recordSecurityEvent records an event, failing to do so only loses the audit trail
+func (s *AuthService) recordSecurityEvent(ctx context.Context, userID uuid.UUID, kind string, sessionID uuid.UUID, client ClientInfo) {
+	client = client.normalize()
+
+	event := &models.SecurityEvent{
+		UserID:    userID,
+		Kind:      kind,
+		SessionID: uuid.NullUUID{UUID: sessionID, Valid: sessionID != uuid.Nil},
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}
+	if err := s.securityRepo.CreateSecurityEvent(ctx, event); err != nil {
+		log.Printf("record %s security event: %v", kind, err)
+	}
+}
+
+// notify emails the user about a change to their account, failures are only logged
+func (s *AuthService) notify(ctx context.Context, to, subject, text string) {
+	if err := s.mailer.Send(ctx, mail.Message{To: to, Subject: subject, Text: text}); err != nil {
+		log.Printf("failed to send %q to %s: %v", subject, to, err)
+	}
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAPITokenNotFound    = errors.New("api token not found")
+	ErrInvalidAPITokenName = errors.New("api token name must be between 1 and 100 characters")
+	ErrInvalidScope        = errors.New("invalid api token scope")
+	ErrInvalidExpiry       = errors.New("api token expiry must be in the future")
+	ErrTooManyAPITokens    = errors.New("too many api tokens")
+)
+
+// Scopes of personal access tokens. Access tokens from a login aren't limited
+// by scopes, personal access tokens only work on routes requiring one they hold.
+const (
+	// ScopeArticlesRead reads feeds, articles and the article stream
+	ScopeArticlesRead = "articles:read"
+	// ScopeArticlesWrite marks articles read, starred or saved and annotates them
+	ScopeArticlesWrite = "articles:write"
+	// ScopeSubscriptionsWrite adds feeds, subscribes, refreshes and imports OPML
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	// ScopeAdmin uses the admin API, the user must still be an administrator
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope a personal access token can be granted
+var Scopes = []string{ScopeArticlesRead, ScopeArticlesWrite, ScopeSubscriptionsWrite, ScopeAdmin}
+
+const (
+	// APITokenPrefix starts every personal access token, telling them apart from JWTs
+	APITokenPrefix = "sws_pat_"
+
+	// The stored prefix includes a few random characters
+	apiTokenDisplayLength = len(APITokenPrefix) + 6
+
+	maxAPITokenNameLength = 100
+	maxAPITokensPerUser   = 50
+)
+
+// IsAPIToken reports whether a bearer token looks like a personal access token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// CreateAPIToken creates a personal access token with the given scopes, expiring
+// at expiresAt unless it is zero. The raw token is only returned here.
+func (s *AuthService) CreateAPIToken(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPITokenNameLength {
+		return nil, "", ErrInvalidAPITokenName
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	var expiry sql.NullTime
+	if !expiresAt.IsZero() {
+		if !expiresAt.After(time.Now()) {
+			return nil, "", ErrInvalidExpiry
+		}
+		expiry = sql.NullTime{Time: expiresAt, Valid: true}
+	}
+
+	count, err := s.apiTokenRepo.CountAPITokens(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxAPITokensPerUser {
+		return nil, "", ErrTooManyAPITokens
+	}
+
+	random, err := randomString(32)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := APITokenPrefix + random
+
+	token, err := s.apiTokenRepo.CreateAPIToken(ctx, userID, name, raw, raw[:apiTokenDisplayLength], scopes, expiry)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return token, raw, nil
+}
+
+// ListAPITokens retrieves the user's personal access tokens that haven't expired, newest first
+func (s *AuthService) ListAPITokens(ctx context.Context, userID uuid.UUID) ([]*models.APIToken, error) {
+	return s.apiTokenRepo.ListAPITokens(ctx, userID)
+}
+
+// RevokeAPIToken deletes one of the user's personal access tokens, it stops working right away
+func (s *AuthService) RevokeAPIToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	if err := s.apiTokenRepo.DeleteAPIToken(ctx, userID, tokenID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPITokenNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ValidateAPIToken looks up a personal access token and records its use from ipAddress.
+// Unknown tokens fail with ErrInvalidToken, expired ones with ErrExpiredToken.
+func (s *AuthService) ValidateAPIToken(ctx context.Context, raw, ipAddress string) (*models.APIToken, error) {
+	if !IsAPIToken(raw) {
+		return nil, ErrInvalidToken
+	}
+
+	token, err := s.apiTokenRepo.GetAPIToken(ctx, raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if token.Expired(time.Now()) {
+		return nil, ErrExpiredToken
+	}
+
+	// Losing the last use doesn't make the token any less valid
+	if err := s.apiTokenRepo.TouchAPIToken(ctx, token.ID, ipAddress); err != nil {
+		log.Printf("failed to record use of api token %s: %v", token.ID, err)
+	}
+
+	return token, nil
+}
+
+// DeleteExpiredAPITokens deletes every expired personal access token in batches,
+// returning how many were deleted
+func (s *AuthService) DeleteExpiredAPITokens(ctx context.Context, batchSize int) (int64, error) {
+	return deleteExpiredInBatches(ctx, batchSize, s.apiTokenRepo.DeleteExpiredAPITokens)
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+)
+
+// KindCleanupRefreshTokens purges refresh tokens, emailed user tokens and personal
+// access tokens that can no longer be used, along with idle rate limits
+const KindCleanupRefreshTokens = "auth.cleanup_refresh_tokens"
+
+// TokenCleanupPayload configures the auth.cleanup_refresh_tokens job
+type TokenCleanupPayload struct {
+	// BatchSize is how many tokens are deleted per statement
+	BatchSize int `json:"batch_size"`
+}
+
+// DeleteExpiredRefreshTokens deletes every expired refresh token in batches,
+// returning how many were deleted
+func (s *AuthService) DeleteExpiredRefreshTokens(ctx context.Context, batchSize int) (int64, error) {
+	return deleteExpiredInBatches(ctx, batchSize, s.refreshTokenRepo.DeleteExpiredRefreshTokens)
+}
+
+// DeleteExpiredUserTokens deletes every expired email verification and similar
+// token in batches, returning how many were deleted
+func (s *AuthService) DeleteExpiredUserTokens(ctx context.Context, batchSize int) (int64, error) {
+	return deleteExpiredInBatches(ctx, batchSize, s.userTokenRepo.DeleteExpiredUserTokens)
+}
+
+// DeleteExpiredRateLimits deletes every rate limit without events in its window
+// in batches, returning how many were deleted
+func (s *AuthService) DeleteExpiredRateLimits(ctx context.Context, batchSize int) (int64, error) {
+	return deleteExpiredInBatches(ctx, batchSize, s.rateLimitRepo.DeleteExpiredRateLimits)
+}
+
+// deleteExpiredInBatches calls deleteBatch until it deletes less than a full batch
+func deleteExpiredInBatches(ctx context.Context, batchSize int, deleteBatch func(ctx context.Context, expiredBefore time.Time, limit int) (int64, error)) (int64, error) {
+	if batchSize < 1 {
+		batchSize = 1000
+	}
+
+	// Fixed cutoff, so tokens expiring while we run don't keep the loop going
+	cutoff := time.Now()
+
+	var deleted int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		n, err := deleteBatch(ctx, cutoff, batchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += n
+		if n < int64(batchSize) {
+			return deleted, nil
+		}
+	}
+}
+
+// RegisterTokenCleanup registers the recurring purge of expired tokens
+func (s *AuthService) RegisterTokenCleanup(jobService *jobs.JobService, interval time.Duration, batchSize int) {
+	jobs.Register(jobService, KindCleanupRefreshTokens, func(ctx context.Context, payload TokenCleanupPayload) error {
+		refreshTokens, err := s.DeleteExpiredRefreshTokens(ctx, payload.BatchSize)
+		if err != nil {
+			log.Printf("Deleted %d expired refresh tokens before failing: %v", refreshTokens, err)
+			return err
+		}
+
+		userTokens, err := s.DeleteExpiredUserTokens(ctx, payload.BatchSize)
+		if err != nil {
+			log.Printf("Deleted %d expired user tokens before failing: %v", userTokens, err)
+			return err
+		}
+
+		apiTokens, err := s.DeleteExpiredAPITokens(ctx, payload.BatchSize)
+		if err != nil {
+			log.Printf("Deleted %d expired api tokens before failing: %v", apiTokens, err)
+			return err
+		}
+
+		rateLimits, err := s.DeleteExpiredRateLimits(ctx, payload.BatchSize)
+		if err != nil {
+			log.Printf("Deleted %d expired rate limits before failing: %v", rateLimits, err)
+			return err
+		}
+
+		log.Printf("Deleted %d expired refresh tokens, %d expired user tokens, %d expired api tokens and %d expired rate limits", refreshTokens, userTokens, apiTokens, rateLimits)
+		return nil
+	})
+
+	jobService.Every(KindCleanupRefreshTokens, interval, TokenCleanupPayload{BatchSize: batchSize})
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// NormalizeEmail trims email and checks it is a bare address such as
+// "user@example.com", returning ErrInvalidEmail otherwise
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	// Require a dotted domain, local delivery addresses are no use to us
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	return email, nil
+}
+
+// humanizeDuration formats durations shown in emails, such as "24 hours" or "30 minutes"
+func humanizeDuration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	if d >= time.Hour && d%time.Hour == 0 {
+		return plural(int64(d/time.Hour), "hour")
+	}
+
+	return plural(int64(d.Round(time.Minute)/time.Minute), "minute")
+}
@@ -0,0 +1,404 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+var (
+	ErrMFARequired       = errors.New("two-factor authentication required")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication not set up")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+)
+
+const (
+	totpIssuer = "Swayamsevak"
+	totpPeriod = 30 * time.Second
+	// Codes from one step before or after the current one are accepted to allow for clock drift
+	totpSkew = 1
+
+	qrCodeSize = 256
+
+	recoveryCodeCount = 10
+
+	// MFA challenges only need to live long enough to type in a code
+	mfaChallengeTTL  = 5 * time.Minute
+	mfaChallengeType = "mfa"
+
+	// Each user may try mfaAttemptLimit codes per mfaAttemptWindow, a 6 digit
+	// code can't be guessed at that rate
+	mfaAttemptLimit  = 5
+	mfaAttemptWindow = 5 * time.Minute
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    uint(totpPeriod / time.Second),
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TOTPEnrollment is a new TOTP secret waiting to be confirmed with a first code
+type TOTPEnrollment struct {
+	Secret string
+	// URI is the otpauth:// URI authenticator apps import
+	URI string
+	// QRCodePNG encodes URI for scanning
+	QRCodePNG []byte
+}
+
+// MFAStatus describes the two-factor authentication of a user
+type MFAStatus struct {
+	TOTPEnabled       bool
+	RecoveryCodesLeft int
+}
+
+// MFAStatus returns whether the user has TOTP on and how many recovery codes they have left
+func (s *AuthService) MFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{TOTPEnabled: user.TOTPEnabled()}
+	if status.TOTPEnabled {
+		if status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// EnrollTOTP creates a TOTP secret for the user after checking their password.
+// It only takes effect once confirmed with ConfirmTOTP, enrolling again replaces
+// an unconfirmed secret.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID uuid.UUID, password string) (*TOTPEnrollment, error) {
+	user, err := s.reauthenticate(userID, password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+		Period:      totpOpts.Period,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.secrets.Seal([]byte(key.Secret()), user.ID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SetPendingTOTPSecret(ctx, user.ID, encrypted); err != nil {
+		if errors.Is(err, models.ErrTOTPAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:    key.Secret(),
+		URI:       key.URL(),
+		QRCodePNG: qr.Bytes(),
+	}, nil
+}
+
+// ConfirmTOTP turns on TOTP with a first code from the authenticator and returns
+// the recovery codes, which are only ever shown here. Every other session is
+// signed out and personal access tokens are deleted, so all remaining
+// credentials were created after passing the second factor.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID, currentSessionID uuid.UUID, code string) ([]string, error) {
+	allowed, err := s.mfaLimiter.allow(ctx, userID.String(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrTooManyRequests
+	}
+
+	settings, err := s.mfaRepo.GetTOTPSettings(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if settings.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, err := s.matchTOTP(settings, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, models.ErrTOTPAlreadyEnabled) {
+			return nil, ErrInvalidMFACode
+		}
+		return nil, err
+	}
+
+	if _, err := s.sessionRepo.RevokeOtherSessions(ctx, userID, currentSessionID); err != nil {
+		return nil, err
+	}
+	// Personal access tokens skip the second factor, ones made before it was on are deleted
+	if _, err := s.apiTokenRepo.DeleteAllAPITokens(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off after checking the password
+// and a code from the authenticator or a recovery code
+func (s *AuthService) DisableTOTP(ctx context.Context, userID uuid.UUID, password, code string) error {
+	if _, err := s.reauthenticate(userID, password); err != nil {
+		return err
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return s.mfaRepo.DisableTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a code
+// from the authenticator, returning the new codes
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.verifySecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CompleteMFALogin finishes a login that was answered with an MFA challenge,
+// exchanging the challenge token and a TOTP or recovery code for a new session
+func (s *AuthService) CompleteMFALogin(ctx context.Context, mfaToken, code string, refreshTokenTTL time.Duration, client ClientInfo) (accessToken string, refreshToken string, err error) {
+	claims, err := s.parseToken(mfaToken)
+	if err != nil {
+		return "", "", err
+	}
+	if typ, _ := claims["typ"].(string); typ != mfaChallengeType {
+		return "", "", ErrInvalidToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, code); err != nil {
+		return "", "", err
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+
+	// The device name was given with the password
+	client.DeviceName, _ = claims["dev"].(string)
+
+	return s.startSession(user, refreshTokenTTL, client)
+}
+
+// generateMFAChallenge creates the token a password login returns for accounts
+// with two-factor authentication, proving the password was already checked
+func (s *AuthService) generateMFAChallenge(user *models.User, deviceName string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"dev": deviceName,
+		"exp": expiresAt.Unix(),
+		"iat": time.Now().Unix(),
+		"typ": mfaChallengeType,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// secondFactorStore marks TOTP steps and recovery codes as used, it is the
+// MFARepository outside of tests
+type secondFactorStore interface {
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash []byte) (bool, error)
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code of
+// the user, each only once
+func (s *AuthService) verifySecondFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	allowed, err := s.mfaLimiter.allow(ctx, userID.String(), time.Now())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyRequests
+	}
+
+	settings, err := s.mfaRepo.GetTOTPSettings(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFANotEnrolled
+		}
+		return err
+	}
+
+	return s.checkSecondFactor(ctx, s.mfaRepo, settings, code, time.Now())
+}
+
+// checkSecondFactor accepts code as a TOTP code or recovery code of settings,
+// marking it used in store
+func (s *AuthService) checkSecondFactor(ctx context.Context, store secondFactorStore, settings *models.TOTPSettings, code string, now time.Time) error {
+	if !settings.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == int(totpOpts.Digits) {
+		step, err := s.matchTOTP(settings, code, now)
+		if err != nil {
+			return err
+		}
+
+		fresh, err := store.UseTOTPStep(ctx, settings.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := store.UseRecoveryCode(ctx, settings.UserID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// matchTOTP returns the time step code belongs to, accepting steps around the
+// current one that are newer than the last used step
+func (s *AuthService) matchTOTP(settings *models.TOTPSettings, code string, now time.Time) (int64, error) {
+	secret, err := s.secrets.Open(settings.EncryptedSecret, settings.UserID[:])
+	if err != nil {
+		return 0, err
+	}
+
+	current := now.Unix() / int64(totpOpts.Period)
+
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		step := current + int64(offset)
+		if step <= settings.LastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(string(secret), time.Unix(step*int64(totpOpts.Period), 0), totpOpts)
+		if err != nil {
+			return 0, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+
+	return 0, ErrInvalidMFACode
+}
+
+// newRecoveryCodes creates a set of recovery codes such as "k7mq-2xrd-9p4a-wz3n"
+// along with the digests to store
+func newRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for range recoveryCodeCount {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(random))
+		code := raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode digests a recovery code, ignoring case, spaces and dashes.
+// The codes carry 80 random bits, so a plain SHA-256 can't be brute forced.
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/secretbox"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+// testTOTPService returns a service able to open the settings' secret
+func testTOTPService(t *testing.T) (*AuthService, *models.TOTPSettings) {
+	t.Helper()
+
+	box, err := secretbox.New("", []byte("test secret"))
+	if err != nil {
+		t.Fatalf("secretbox.New: %v", err)
+	}
+
+	userID := uuid.New()
+	sealed, err := box.Seal([]byte(testTOTPSecret), userID[:])
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	return &AuthService{secrets: box}, &models.TOTPSettings{
+		UserID:          userID,
+		EncryptedSecret: sealed,
+		Enabled:         true,
+	}
+}
+
+// totpCode returns the code of the given time step
+func totpCode(t *testing.T, step int64) string {
+	t.Helper()
+
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, time.Unix(step*int64(totpOpts.Period), 0), totpOpts)
+	if err != nil {
+		t.Fatalf("GenerateCodeCustom: %v", err)
+	}
+	return code
+}
+
+// wrongTOTPCode returns a code matching none of the steps around current
+func wrongTOTPCode(t *testing.T, current int64) string {
+	t.Helper()
+
+	code := []byte(totpCode(t, current))
+	for {
+		code[len(code)-1] = '0' + (code[len(code)-1]-'0'+1)%10
+		if string(code) != totpCode(t, current-1) && string(code) != totpCode(t, current+1) {
+			return string(code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	s, settings := testTOTPService(t)
+
+	now := time.Unix(1_700_000_010, 0)
+	current := now.Unix() / int64(totpOpts.Period)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		want     int64
+		wantErr  error
+	}{
+		{"current step", totpCode(t, current), 0, current, nil},
+		{"previous step", totpCode(t, current-1), 0, current - 1, nil},
+		{"next step", totpCode(t, current+1), 0, current + 1, nil},
+		{"two steps old", totpCode(t, current-2), 0, 0, ErrInvalidMFACode},
+		{"two steps ahead", totpCode(t, current+2), 0, 0, ErrInvalidMFACode},
+		{"wrong code", wrongTOTPCode(t, current), 0, 0, ErrInvalidMFACode},
+		{"replayed step", totpCode(t, current), current, 0, ErrInvalidMFACode},
+		{"step before last used", totpCode(t, current-1), current - 1, 0, ErrInvalidMFACode},
+		{"step after last used", totpCode(t, current), current - 1, current, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := *settings
+			settings.LastStep = tt.lastStep
+
+			got, err := s.matchTOTP(&settings, tt.code, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("matchTOTP() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("matchTOTP() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPWrongSecret(t *testing.T) {
+	s, settings := testTOTPService(t)
+
+	// Sealed for another user
+	settings.UserID = uuid.New()
+
+	now := time.Unix(1_700_000_010, 0)
+	_, err := s.matchTOTP(settings, totpCode(t, now.Unix()/int64(totpOpts.Period)), now)
+	if err == nil || errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("matchTOTP() error = %v, want a decryption error", err)
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("k7mq-2xrd-9p4a-wz3n")
+
+	tests := []struct {
+		name string
+		code string
+		same bool
+	}{
+		{"same code", "k7mq-2xrd-9p4a-wz3n", true},
+		{"upper case", "K7MQ-2XRD-9P4A-WZ3N", true},
+		{"without dashes", "k7mq2xrd9p4awz3n", true},
+		{"spaces instead of dashes", "k7mq 2xrd 9p4a wz3n", true},
+		{"surrounding spaces", "  k7mq-2xrd-9p4a-wz3n ", true},
+		{"mixed", " K7mq 2XRD-9p4a wz3N", true},
+		{"other code", "k7mq-2xrd-9p4a-wz3m", false},
+		{"truncated", "k7mq-2xrd-9p4a", false},
+		{"other separator", "k7mq_2xrd_9p4a_wz3n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bytes.Equal(hashRecoveryCode(tt.code), want); got != tt.same {
+				t.Errorf("hashRecoveryCode(%q) matches = %v, want %v", tt.code, got, tt.same)
+			}
+		})
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("newRecoveryCodes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	for i, code := range codes {
+		if !bytes.Equal(hashRecoveryCode(code), hashes[i]) {
+			t.Errorf("hash %d doesn't match code %q", i, code)
+		}
+	}
+}
+
+// fakeSecondFactorStore keeps used steps and recovery codes in memory
+type fakeSecondFactorStore struct {
+	lastStep int64
+	// recoveryCodes holds the digests of unused codes
+	recoveryCodes map[string]bool
+}
+
+func (f *fakeSecondFactorStore) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	if step <= f.lastStep {
+		return false, nil
+	}
+	f.lastStep = step
+	return true, nil
+}
+
+func (f *fakeSecondFactorStore) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash []byte) (bool, error) {
+	if !f.recoveryCodes[string(codeHash)] {
+		return false, nil
+	}
+	delete(f.recoveryCodes, string(codeHash))
+	return true, nil
+}
+
+func TestVerifySecondFactor(t *testing.T) {
+	s, settings := testTOTPService(t)
+
+	now := time.Unix(1_700_000_010, 0)
+	current := now.Unix() / int64(totpOpts.Period)
+	recoveryCode := "k7mq-2xrd-9p4a-wz3n"
+
+	tests := []struct {
+		name     string
+		code     string
+		disabled bool
+		// usedStep is the last step marked used, e.g. by a concurrent login
+		usedStep int64
+		// recoveryUsed means the recovery code was used before
+		recoveryUsed bool
+		wantErr      error
+	}{
+		{"totp code", totpCode(t, current), false, 0, false, nil},
+		{"totp code with spaces", " " + totpCode(t, current) + " ", false, 0, false, nil},
+		{"totp code of previous step", totpCode(t, current-1), false, 0, false, nil},
+		{"wrong totp code", wrongTOTPCode(t, current), false, 0, false, ErrInvalidMFACode},
+		{"totp step used concurrently", totpCode(t, current), false, current, false, ErrInvalidMFACode},
+		{"recovery code", recoveryCode, false, 0, false, nil},
+		{"recovery code reformatted", "K7MQ 2XRD 9P4A WZ3N", false, 0, false, nil},
+		{"recovery code used before", recoveryCode, false, 0, true, ErrInvalidMFACode},
+		{"unknown recovery code", "aaaa-bbbb-cccc-dddd", false, 0, false, ErrInvalidMFACode},
+		{"not enabled", totpCode(t, current), true, 0, false, ErrMFANotEnrolled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := *settings
+			settings.Enabled = !tt.disabled
+
+			store := &fakeSecondFactorStore{
+				lastStep:      tt.usedStep,
+				recoveryCodes: map[string]bool{string(hashRecoveryCode(recoveryCode)): !tt.recoveryUsed},
+			}
+
+			err := s.checkSecondFactor(context.Background(), store, &settings, tt.code, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkSecondFactor() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySecondFactorOnlyOnce(t *testing.T) {
+	s, settings := testTOTPService(t)
+
+	now := time.Unix(1_700_000_010, 0)
+	code := totpCode(t, now.Unix()/int64(totpOpts.Period))
+	recoveryCode := "k7mq-2xrd-9p4a-wz3n"
+	store := &fakeSecondFactorStore{
+		recoveryCodes: map[string]bool{string(hashRecoveryCode(recoveryCode)): true},
+	}
+
+	for _, code := range []string{code, recoveryCode} {
+		if err := s.checkSecondFactor(context.Background(), store, settings, code, now); err != nil {
+			t.Fatalf("first use of %q: %v", code, err)
+		}
+		// settings still hold the old LastStep, as for a login racing the first
+		if err := s.checkSecondFactor(context.Background(), store, settings, code, now); !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("second use of %q: error = %v, want ErrInvalidMFACode", code, err)
+		}
+	}
+}
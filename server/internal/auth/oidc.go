@@ -0,0 +1,364 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrOIDCDisabled         = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState     = errors.New("invalid or expired single sign-on state")
+	ErrOIDCLoginFailed      = errors.New("single sign-on failed")
+	ErrOIDCEmailNotVerified = errors.New("the provider did not verify the email address")
+	ErrOIDCNoAccount        = errors.New("no account for this email address")
+	ErrOIDCAccountConflict  = errors.New("an unverified account uses this email address")
+)
+
+const (
+	// OIDCFlowTTL is how long a login has to come back from the provider
+	OIDCFlowTTL = 10 * time.Minute
+
+	oidcHTTPTimeout = 10 * time.Second
+
+	// Additional data binding sealed flow states to their purpose
+	oidcFlowAD = "oidc flow"
+
+	// Attempts at finding a free username for a provisioned user
+	provisionUsernameAttempts = 5
+)
+
+// OIDCConfig configures login with an OpenID Connect provider. Login is
+// disabled while IssuerURL is empty.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is our callback the provider sends the browser back to
+	RedirectURL string
+	Scopes      []string
+	// AutoProvision creates accounts for unknown users instead of turning them away
+	AutoProvision bool
+}
+
+// oidcClient talks to the OpenID Connect provider. Discovery runs on first use,
+// so the API starts even while the provider is unreachable.
+type oidcClient struct {
+	cfg        OIDCConfig
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func newOIDCClient(cfg OIDCConfig) *oidcClient {
+	if cfg.IssuerURL == "" {
+		return nil
+	}
+
+	return &oidcClient{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// discover fetches the provider metadata once, its JWKS are fetched and
+// refreshed by the verifier when it meets a new key ID
+func (c *oidcClient) discover() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.oauth != nil {
+		return c.oauth, c.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(c.context(context.Background()), c.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discovering %s: %w", c.cfg.IssuerURL, err)
+	}
+
+	c.oauth = &oauth2.Config{
+		ClientID:     c.cfg.ClientID,
+		ClientSecret: c.cfg.ClientSecret,
+		RedirectURL:  c.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       c.cfg.Scopes,
+	}
+	c.verifier = provider.Verifier(&oidc.Config{ClientID: c.cfg.ClientID})
+
+	return c.oauth, c.verifier, nil
+}
+
+// context makes the oauth2 and oidc packages use our HTTP client
+func (c *oidcClient) context(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, c.httpClient)
+}
+
+// oidcFlow is what a login needs to remember while the browser is at the provider
+type oidcFlow struct {
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcClaims are the ID token claims we use
+type oidcClaims struct {
+	Email             string    `json:"email"`
+	EmailVerified     claimBool `json:"email_verified"`
+	PreferredUsername string    `json:"preferred_username"`
+	Name              string    `json:"name"`
+}
+
+// claimBool accepts booleans some providers send as strings
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = claimBool(strings.EqualFold(v, "true"))
+	default:
+		return fmt.Errorf("invalid boolean claim %s", data)
+	}
+	return nil
+}
+
+// OIDCEnabled reports whether login with an OpenID Connect provider is configured
+func (s *AuthService) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// StartOIDCLogin begins an authorization code login with PKCE. It returns the
+// provider URL to send the browser to and the sealed flow state the client must
+// hand back to CompleteOIDCLogin, such as in a cookie.
+func (s *AuthService) StartOIDCLogin(ctx context.Context) (authURL string, flowState string, err error) {
+	if s.oidc == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	oauthCfg, _, err := s.oidc.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	flow := oidcFlow{
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(OIDCFlowTTL),
+	}
+
+	payload, err := json.Marshal(flow)
+	if err != nil {
+		return "", "", err
+	}
+
+	sealed, err := s.secrets.Seal(payload, []byte(oidcFlowAD))
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL = oauthCfg.AuthCodeURL(flow.State, oidc.Nonce(flow.Nonce), oauth2.S256ChallengeOption(flow.Verifier))
+	return authURL, base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// CompleteOIDCLogin handles the provider's redirect back to us. The state must
+// match the flow started by StartOIDCLogin, the code is exchanged with the PKCE
+// verifier and the ID token is checked against the provider's keys and our nonce.
+// The identity is then resolved to a user, who is signed in like after a password
+// login, including the MFA challenge for users with two-factor authentication.
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, flowState, state, code string, refreshTokenTTL time.Duration, client ClientInfo) (*LoginResult, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	flow, err := s.openOIDCFlow(flowState)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, ErrOIDCInvalidState
+	}
+
+	oauthCfg, verifier, err := s.oidc.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = s.oidc.context(ctx)
+
+	token, err := oauthCfg.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: exchanging code: %v", ErrOIDCLoginFailed, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: no id_token in token response", ErrOIDCLoginFailed)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: verifying id_token: %v", ErrOIDCLoginFailed, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(flow.Nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCLoginFailed)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: reading claims: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err := s.resolveOIDCUser(ctx, idToken.Issuer, idToken.Subject, &claims, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.finishLogin(user, refreshTokenTTL, client)
+}
+
+// openOIDCFlow unseals a flow state created by StartOIDCLogin
+func (s *AuthService) openOIDCFlow(flowState string) (*oidcFlow, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(flowState)
+	if err != nil {
+		return nil, ErrOIDCInvalidState
+	}
+
+	payload, err := s.secrets.Open(sealed, []byte(oidcFlowAD))
+	if err != nil {
+		return nil, ErrOIDCInvalidState
+	}
+
+	var flow oidcFlow
+	if err := json.Unmarshal(payload, &flow); err != nil {
+		return nil, ErrOIDCInvalidState
+	}
+	if time.Now().After(flow.ExpiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	return &flow, nil
+}
+
+// resolveOIDCUser finds the user of a provider account. Accounts seen before are
+// found by their identity, new ones are linked to the user with the same verified
+// email or, with AutoProvision, get a new user.
+func (s *AuthService) resolveOIDCUser(ctx context.Context, issuer, subject string, claims *oidcClaims, client ClientInfo) (*models.User, error) {
+	email, emailErr := NormalizeEmail(claims.Email)
+
+	identity, err := s.identityRepo.GetIdentity(ctx, issuer, subject)
+	if err == nil {
+		if emailErr == nil {
+			if err := s.identityRepo.TouchIdentity(ctx, identity.ID, email); err != nil {
+				log.Printf("oidc: failed to update identity %s: %v", identity.ID, err)
+			}
+		}
+		return s.userRepo.GetUserByID(identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Linking trusts the provider's word that the address belongs to the caller
+	if emailErr != nil || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetUserByEmail(email)
+	switch {
+	case err == nil:
+		// Someone may have registered the address without owning it, they
+		// must not get access to the provider account's login
+		if !user.EmailVerified() {
+			return nil, ErrOIDCAccountConflict
+		}
+
+		if _, err := s.identityRepo.LinkIdentity(ctx, user.ID, issuer, subject, email); err != nil {
+			return nil, err
+		}
+
+		s.recordSecurityEvent(ctx, user.ID, models.SecurityEventIdentityLinked, uuid.Nil, client)
+		s.notify(ctx, user.Email, "Single sign-on was linked to your account",
+			"Your account can now be signed in to through your organization's single sign-on.\n\n"+
+				"If this wasn't you, contact your administrator.\n")
+
+		return user, nil
+	case errors.Is(err, sql.ErrNoRows):
+		if !s.oidc.cfg.AutoProvision {
+			return nil, ErrOIDCNoAccount
+		}
+		return s.provisionOIDCUser(ctx, email, issuer, subject, claims)
+	default:
+		return nil, err
+	}
+}
+
+// provisionOIDCUser creates a user for a provider account. The user has no
+// password, so password sign-in fails and changes that ask for the password
+// return ErrPasswordNotSet until they set one with a password reset.
+func (s *AuthService) provisionOIDCUser(ctx context.Context, email, issuer, subject string, claims *oidcClaims) (*models.User, error) {
+	base := strings.TrimSpace(claims.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+
+	username := base
+	for range provisionUsernameAttempts {
+		user, err := s.identityRepo.CreateUserWithIdentity(ctx, email, username, "", issuer, subject)
+		if !errors.Is(err, models.ErrUsernameTaken) {
+			return user, err
+		}
+
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		username = base + "-" + hex.EncodeToString(suffix)
+	}
+
+	return nil, models.ErrUsernameTaken
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
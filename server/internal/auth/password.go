@@ -1,6 +1,14 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidPassword = errors.New("password must be between 8 and 72 bytes long")
+)
 
 // HashPassword creates a bcrypt hash from a plain-text password
 func HashPassword(password string) (string, error) {
@@ -18,3 +26,19 @@ func HashPassword(password string) (string, error) {
 func VerifyPassword(hashedPassword, providedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(providedPassword))
 }
+
+// Length limits of new passwords in bytes. bcrypt only hashes the first 72
+// bytes, so longer passwords are rejected rather than silently truncated.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// ValidatePassword checks a new password is long enough and fits into a bcrypt hash
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
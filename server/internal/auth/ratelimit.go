@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+)
+
+// rateLimiter allows up to limit events per key within a sliding window. Events
+// are stored in the database, so the limit holds across every API instance.
+type rateLimiter struct {
+	repo *models.RateLimitRepository
+	// name keeps the keys of different limiters apart
+	name   string
+	limit  int
+	window time.Duration
+}
+
+func newRateLimiter(repo *models.RateLimitRepository, name string, limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		repo:   repo,
+		name:   name,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// allow records an event for key and reports whether it is within the limit
+func (l *rateLimiter) allow(ctx context.Context, key string, now time.Time) (bool, error) {
+	return l.repo.UpdateRateLimit(ctx, l.name+":"+key, now.Add(l.window), func(events []time.Time) ([]time.Time, bool) {
+		return l.slide(events, now)
+	})
+}
+
+// slide drops the events that left the window ending at now and records now if
+// fewer than limit events remain. It reports whether now was recorded.
+func (l *rateLimiter) slide(events []time.Time, now time.Time) ([]time.Time, bool) {
+	cutoff := now.Add(-l.window)
+
+	recent := make([]time.Time, 0, len(events)+1)
+	for _, t := range events {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		return recent, false
+	}
+
+	// Stored without a time zone
+	return append(recent, now.UTC()), true
+}
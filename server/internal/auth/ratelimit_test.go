@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSlide(t *testing.T) {
+	l := &rateLimiter{limit: 3, window: time.Minute}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ago := func(d time.Duration) time.Time {
+		return now.Add(-d)
+	}
+
+	tests := []struct {
+		name      string
+		events    []time.Time
+		allowed   bool
+		wantCount int
+	}{
+		{"no events", nil, true, 1},
+		{"below limit", []time.Time{ago(30 * time.Second), ago(10 * time.Second)}, true, 3},
+		{"at limit", []time.Time{ago(50 * time.Second), ago(30 * time.Second), ago(time.Second)}, false, 3},
+		{"oldest left the window", []time.Time{ago(2 * time.Minute), ago(30 * time.Second), ago(time.Second)}, true, 3},
+		{"event exactly at the window start", []time.Time{ago(time.Minute), ago(30 * time.Second), ago(time.Second)}, true, 3},
+		{"all events left the window", []time.Time{ago(5 * time.Minute), ago(4 * time.Minute), ago(3 * time.Minute)}, true, 1},
+		{"event at now counts", []time.Time{ago(20 * time.Second), ago(10 * time.Second), now}, false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, allowed := l.slide(tt.events, now)
+			if allowed != tt.allowed {
+				t.Errorf("slide() allowed = %v, want %v", allowed, tt.allowed)
+			}
+			if len(events) != tt.wantCount {
+				t.Errorf("slide() kept %d events, want %d", len(events), tt.wantCount)
+			}
+			if allowed && !events[len(events)-1].Equal(now) {
+				t.Errorf("slide() last event = %v, want %v", events[len(events)-1], now)
+			}
+			if events == nil {
+				t.Errorf("slide() returned nil events, the column is NOT NULL")
+			}
+		})
+	}
+}
+
+func TestRateLimiterSlideOverTime(t *testing.T) {
+	l := &rateLimiter{limit: 2, window: time.Minute}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.FixedZone("IST", 5*60*60+30*60))
+
+	steps := []struct {
+		after   time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{10 * time.Second, true},
+		{20 * time.Second, false},
+		// The first event leaves the window, the rejected one was never recorded
+		{61 * time.Second, true},
+		{65 * time.Second, false},
+		{71 * time.Second, true},
+		{3 * time.Minute, true},
+	}
+
+	var events []time.Time
+	for _, step := range steps {
+		var allowed bool
+		events, allowed = l.slide(events, start.Add(step.after))
+		if allowed != step.allowed {
+			t.Fatalf("after %s: allowed = %v, want %v", step.after, allowed, step.allowed)
+		}
+	}
+
+	for _, event := range events {
+		if event.Location() != time.UTC {
+			t.Errorf("event %v isn't UTC, it is stored without a time zone", event)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+const (
+	// Used when Config.PasswordResetTTL is not set
+	defaultPasswordResetTTL = 30 * time.Minute
+
+	// An account gets at most one reset email per passwordResetInterval
+	passwordResetInterval = time.Minute
+
+	// Each client IP may request passwordResetRateLimit reset emails and attempt
+	// as many resets per passwordResetRateWindow
+	passwordResetRateLimit  = 5
+	passwordResetRateWindow = 15 * time.Minute
+)
+
+// KindSendPasswordReset emails a password reset link if the address has an account
+const KindSendPasswordReset = "auth.password_reset"
+
+// PasswordResetPayload is the auth.password_reset payload
+type PasswordResetPayload struct {
+	Email string `json:"email"`
+}
+
+// ForgotPassword emails a password reset link to the account registered with
+// email. To not reveal which emails have an account, every well-formed address
+// is queued the same way and the lookup happens in the background, so unknown
+// or throttled addresses succeed without sending anything and take as long.
+// Only the per-client rate limit fails, with ErrTooManyRequests.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string, client ClientInfo) error {
+	client = client.normalize()
+
+	allowed, err := s.forgotLimiter.allow(ctx, client.IPAddress, time.Now())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyRequests
+	}
+
+	email, err = NormalizeEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	_, err = s.jobService.Enqueue(ctx, KindSendPasswordReset, PasswordResetPayload{Email: email}, jobs.EnqueueOptions{})
+	return err
+}
+
+// sendPasswordReset runs the auth.password_reset job
+func (s *AuthService) sendPasswordReset(ctx context.Context, payload PasswordResetPayload) error {
+	user, err := s.userRepo.GetUserByEmail(payload.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	last, err := s.userTokenRepo.GetLatestUserToken(ctx, user.ID, models.UserTokenPasswordReset)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if last != nil && time.Since(last.CreatedAt) < passwordResetInterval {
+		return nil
+	}
+
+	ttl := s.passwordResetTTL
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+
+	token, digest, err := s.newUserToken(models.UserTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.userTokenRepo.CreateUserToken(ctx, user.ID, models.UserTokenPasswordReset, digest, user.Email, time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	link := s.appBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your account. To choose a new password, open the link below:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s and works once. If you didn't ask for this, you can ignore this email, your password stays the same.\n",
+			user.Username, link, humanizeDuration(ttl)),
+	})
+}
+
+// ResetPassword sets a new password with a token from a reset email. Every
+// session and personal access token of the user is revoked in the same
+// transaction, so a stolen session doesn't survive the reset.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string, client ClientInfo) error {
+	client = client.normalize()
+
+	allowed, err := s.resetLimiter.allow(ctx, client.IPAddress, time.Now())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyRequests
+	}
+
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	digest, ok := s.verifyUserToken(models.UserTokenPasswordReset, token)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// Fails as well when the email changed since the link was sent
+	userToken, err := s.userRepo.ResetPasswordWithToken(ctx, digest, hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	s.recordSecurityEvent(ctx, userToken.UserID, models.SecurityEventPasswordReset, uuid.Nil, client)
+
+	// Let the owner know in case they didn't do this themselves
+	s.notify(ctx, userToken.Email, "Your password was changed",
+		"Hi,\n\n"+
+			"The password of your account was just reset and every device was signed out.\n\n"+
+			"If this wasn't you, reset your password again right away.\n")
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrUserNotFound = errors.New("user not found")
+	ErrLastAdmin    = errors.New("cannot remove the last admin")
+	ErrDeleteSelf   = errors.New("admins can't delete themselves from the admin API")
+)
+
+// Roles lists every role a user can have
+var Roles = []string{models.RoleUser, models.RoleAdmin}
+
+// ListUsers retrieves a page of users ordered by email, along with the total number of users
+func (s *AuthService) ListUsers(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+	return s.userRepo.ListUsers(ctx, limit, offset)
+}
+
+// GetUser retrieves any user by ID
+func (s *AuthService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetUserRole changes the role of a user. Demotions apply right away, the role
+// claim of access tokens already issued is only trusted to reject requests.
+func (s *AuthService) SetUserRole(ctx context.Context, userID uuid.UUID, role string, client ClientInfo) (*models.User, error) {
+	if !slices.Contains(Roles, role) {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.userRepo.SetUserRole(ctx, userID, role); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrUserNotFound
+		case errors.Is(err, models.ErrLastAdmin):
+			return nil, ErrLastAdmin
+		}
+		return nil, err
+	}
+
+	s.recordSecurityEvent(ctx, userID, models.SecurityEventRoleChanged, uuid.Nil, client)
+
+	return s.GetUser(ctx, userID)
+}
+
+// DeleteUser deletes another user's account on behalf of the admin adminID
+func (s *AuthService) DeleteUser(ctx context.Context, adminID, userID uuid.UUID) error {
+	if adminID == userID {
+		return ErrDeleteSelf
+	}
+
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrUserNotFound
+		case errors.Is(err, models.ErrLastAdmin):
+			return ErrLastAdmin
+		}
+		return err
+	}
+
+	return nil
+}
+
+// PromoteAdmins gives the admin role to the users among emails who verified
+// their address while no admin exists, returning how many were promoted. It
+// only bootstraps the first admins, so it is safe to run on every start and
+// never undoes a later demotion.
+func (s *AuthService) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	return s.userRepo.PromoteAdmins(ctx, emails)
+}
@@ -1,43 +1,130 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/secretbox"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrPasswordNotSet     = errors.New("account has no password, set one with a password reset")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrExpiredToken       = errors.New("token has expired")
 	ErrEmailInUse         = errors.New("email already in use")
+	ErrInvalidEmail       = errors.New("invalid email address")
 )
 
+// The "typ" claim tells access tokens apart from other tokens signed with the same secret
+const accessTokenType = "access"
+
+// Stream tickets only need to live long enough to open an EventSource
+const streamTicketTTL = time.Minute
+
+// Config holds the settings of the AuthService
+type Config struct {
+	JWTSecret      string
+	AccessTokenTTL time.Duration
+	// AppBaseURL is the frontend links in emails point to, e.g. "https://app.example.com"
+	AppBaseURL string
+	// EmailVerificationTTL is how long email verification links stay valid
+	EmailVerificationTTL time.Duration
+	// PasswordResetTTL is how long password reset links stay valid
+	PasswordResetTTL time.Duration
+	// SecretKey is the base64 encoded 32 byte key encrypting TOTP secrets. When
+	// empty a key is derived from JWTSecret.
+	SecretKey string
+	// OIDC configures login with an OpenID Connect provider
+	OIDC OIDCConfig
+}
+
 // AuthService provides authentication functionality
 type AuthService struct {
 	userRepo         *models.UserRepository
 	refreshTokenRepo *models.RefreshTokenRepository
-	jwtSecret        []byte
-	accessTokenTTL   time.Duration
+	sessionRepo      *models.SessionRepository
+	securityRepo     *models.SecurityEventRepository
+	userTokenRepo    *models.UserTokenRepository
+	mfaRepo          *models.MFARepository
+	identityRepo     *models.IdentityRepository
+	apiTokenRepo     *models.APITokenRepository
+	rateLimitRepo    *models.RateLimitRepository
+	mailer           mail.Mailer
+	jobService       *jobs.JobService
+	secrets          *secretbox.Box
+	// oidc is nil unless an OpenID Connect provider is configured
+	oidc *oidcClient
+
+	jwtSecret            []byte
+	accessTokenTTL       time.Duration
+	appBaseURL           string
+	emailVerificationTTL time.Duration
+	passwordResetTTL     time.Duration
+
+	// Per client IP limits on requesting and using password reset links, shared
+	// by every instance
+	forgotLimiter *rateLimiter
+	resetLimiter  *rateLimiter
+	// Per user limit on second factor attempts
+	mfaLimiter *rateLimiter
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(userRepo *models.UserRepository, refreshTokenRepo *models.RefreshTokenRepository, jwtSecret string, accessTokenTTL time.Duration) *AuthService {
-	return &AuthService{
-		userRepo:         userRepo,
-		refreshTokenRepo: refreshTokenRepo,
-		jwtSecret:        []byte(jwtSecret),
-		accessTokenTTL:   accessTokenTTL,
+func NewAuthService(userRepo *models.UserRepository, refreshTokenRepo *models.RefreshTokenRepository, sessionRepo *models.SessionRepository, securityRepo *models.SecurityEventRepository, userTokenRepo *models.UserTokenRepository, mfaRepo *models.MFARepository, identityRepo *models.IdentityRepository, apiTokenRepo *models.APITokenRepository, rateLimitRepo *models.RateLimitRepository, mailer mail.Mailer, jobService *jobs.JobService, cfg Config) (*AuthService, error) {
+	secrets, err := secretbox.New(cfg.SecretKey, []byte(cfg.JWTSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &AuthService{
+		userRepo:             userRepo,
+		refreshTokenRepo:     refreshTokenRepo,
+		sessionRepo:          sessionRepo,
+		securityRepo:         securityRepo,
+		userTokenRepo:        userTokenRepo,
+		mfaRepo:              mfaRepo,
+		identityRepo:         identityRepo,
+		apiTokenRepo:         apiTokenRepo,
+		rateLimitRepo:        rateLimitRepo,
+		mailer:               mailer,
+		jobService:           jobService,
+		secrets:              secrets,
+		oidc:                 newOIDCClient(cfg.OIDC),
+		jwtSecret:            []byte(cfg.JWTSecret),
+		accessTokenTTL:       cfg.AccessTokenTTL,
+		appBaseURL:           strings.TrimRight(cfg.AppBaseURL, "/"),
+		emailVerificationTTL: cfg.EmailVerificationTTL,
+		passwordResetTTL:     cfg.PasswordResetTTL,
+		forgotLimiter:        newRateLimiter(rateLimitRepo, "password_forgot", passwordResetRateLimit, passwordResetRateWindow),
+		resetLimiter:         newRateLimiter(rateLimitRepo, "password_reset", passwordResetRateLimit, passwordResetRateWindow),
+		mfaLimiter:           newRateLimiter(rateLimitRepo, "mfa", mfaAttemptLimit, mfaAttemptWindow),
 	}
+
+	jobs.Register(jobService, KindSendPasswordReset, s.sendPasswordReset)
+
+	return s, nil
 }
 
-// Register creates a new user with the provided credentials
+// Register creates a new user with the provided credentials and emails them a
+// verification link. The account stays unverified until the link is followed.
 func (s *AuthService) Register(email, username, password string) (*models.User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if the user already exists
-	_, err := s.userRepo.GetUserByEmail(email)
+	_, err = s.userRepo.GetUserByEmail(email)
 	if err == nil {
 		return nil, ErrEmailInUse
 	}
@@ -59,10 +146,16 @@ func (s *AuthService) Register(email, username, password string) (*models.User,
 		return nil, err
 	}
 
+	// The account exists either way, the user can ask for another email
+	if err := s.sendVerificationEmail(context.Background(), user, user.Email); err != nil {
+		log.Printf("register: failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	return user, nil
 }
 
-// Login authenticates the user and returns an access token
+// Login authenticates the user and returns an access token. Accounts with
+// two-factor authentication fail with ErrMFARequired, they must use LoginWithRefresh.
 func (s *AuthService) Login(email, password string) (string, error) {
 	// Get the user from the database
 	user, err := s.userRepo.GetUserByEmail(email)
@@ -75,13 +168,17 @@ func (s *AuthService) Login(email, password string) (string, error) {
 		return "", ErrInvalidCredentials
 	}
 
+	if user.TOTPEnabled() {
+		return "", ErrMFARequired
+	}
+
 	// Update last_login and updated_at
 	// We don't write error handling here which is returned from UpdateLastLogin
 	// because we don't want to block user login even if update fails
 	_ = s.userRepo.UpdateLastLogin(user.ID)
 
 	// Generate an access token
-	token, err := s.generateAccessToken(user)
+	token, err := s.generateAccessToken(user, uuid.Nil)
 	if err != nil {
 		return "", err
 	}
@@ -89,8 +186,10 @@ func (s *AuthService) Login(email, password string) (string, error) {
 	return token, nil
 }
 
-// generateAccessToken creates a new JWT access token
-func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
+// generateAccessToken creates a new JWT access token. Tokens issued for a
+// session carry its ID in the "sid" claim, and every token carries the user's
+// role in the "role" claim.
+func (s *AuthService) generateAccessToken(user *models.User, sessionID uuid.UUID) (string, error) {
 	// Set the expiration time
 	expirationTime := time.Now().Add(s.accessTokenTTL)
 
@@ -101,6 +200,11 @@ func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
 		"email":    user.Email,            // custom claim
 		"exp":      expirationTime.Unix(), // expiration time
 		"iat":      time.Now().Unix(),     // issued at time
+		"typ":      accessTokenType,       // token type
+		"role":     user.Role,             // role at issue time
+	}
+	if sessionID != uuid.Nil {
+		claims["sid"] = sessionID
 	}
 
 	// Create the token with the claims
@@ -115,8 +219,61 @@ func (s *AuthService) generateAccessToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken verifies a JWT token and returns the claims
+// ValidateToken verifies a JWT access token and returns the claims
 func (s *AuthService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before the typ claim existed are access tokens
+	if typ, ok := claims["typ"].(string); ok && typ != accessTokenType {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// GenerateStreamTicket creates a short-lived token that authenticates the user
+// on the article stream only. EventSource can't send an Authorization header,
+// so the ticket is passed in the URL instead of the access token. Tickets are
+// single-use, as URLs end up in proxy logs.
+func (s *AuthService) GenerateStreamTicket(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	ticket, digest, err := s.newUserToken(models.UserTokenStreamTicket)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if _, err := s.userTokenRepo.AddUserToken(ctx, userID, models.UserTokenStreamTicket, digest, "", expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return ticket, expiresAt, nil
+}
+
+// ValidateStreamTicket redeems a stream ticket and returns the user it was
+// issued to. A ticket works only once.
+func (s *AuthService) ValidateStreamTicket(ctx context.Context, ticket string) (uuid.UUID, error) {
+	digest, ok := s.verifyUserToken(models.UserTokenStreamTicket, ticket)
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	token, err := s.userTokenRepo.ConsumeUserToken(ctx, models.UserTokenStreamTicket, digest)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrInvalidToken
+		}
+		return uuid.Nil, err
+	}
+
+	return token.UserID, nil
+}
+
+// parseToken verifies the signature and expiry of a JWT and returns its claims
+func (s *AuthService) parseToken(tokenString string) (jwt.MapClaims, error) {
 	// Parse the token
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
 		// Validate the signing method
@@ -141,36 +298,84 @@ func (s *AuthService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 	return nil, ErrInvalidToken
 }
 
-// LoginWithRefresh authenticates a user and returns both access and refresh tokens
-func (s *AuthService) LoginWithRefresh(email, password string, refreshTokenTTL time.Duration) (accessToken string, refreshToken string, err error) {
+// LoginResult is the outcome of a password login. Accounts with two-factor
+// authentication get an MFA challenge instead of tokens, which CompleteMFALogin
+// exchanges for tokens together with a code.
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+
+	MFAToken     string
+	MFAExpiresAt time.Time
+}
+
+// MFARequired reports whether the login still needs a second factor
+func (r *LoginResult) MFARequired() bool {
+	return r.MFAToken != ""
+}
+
+// LoginWithRefresh authenticates a user and starts a new session for the client,
+// returning both access and refresh tokens. Sessions on other devices are left alone.
+func (s *AuthService) LoginWithRefresh(email, password string, refreshTokenTTL time.Duration, client ClientInfo) (*LoginResult, error) {
 	// Get the user from the database
 	user, err := s.userRepo.GetUserByEmail(email)
 	if err != nil {
-		return "", "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify the password
 	if err := VerifyPassword(user.PasswordHash, password); err != nil {
-		return "", "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
+	return s.finishLogin(user, refreshTokenTTL, client)
+}
+
+// finishLogin signs in a user whose first factor checked out, starting a session
+// or handing out an MFA challenge when they have two-factor authentication
+func (s *AuthService) finishLogin(user *models.User, refreshTokenTTL time.Duration, client ClientInfo) (*LoginResult, error) {
 	// Update last_login and updated_at
 	// We don't write error handling here which is returned from UpdateLastLogin
 	// because we don't want to block user login even if update fails
 	_ = s.userRepo.UpdateLastLogin(user.ID)
 
-	if err := s.refreshTokenRepo.RevokeAllForUser(user.ID); err != nil {
+	// The first factor alone isn't enough, no session until the second factor checks out
+	if user.TOTPEnabled() {
+		mfaToken, expiresAt, err := s.generateMFAChallenge(user, client.normalize().DeviceName)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LoginResult{MFAToken: mfaToken, MFAExpiresAt: expiresAt}, nil
+	}
+
+	accessToken, refreshToken, err := s.startSession(user, refreshTokenTTL, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// startSession creates a session for the client and issues its first token pair
+func (s *AuthService) startSession(user *models.User, refreshTokenTTL time.Duration, client ClientInfo) (accessToken string, refreshToken string, err error) {
+	ctx := context.Background()
+	client = client.normalize()
+
+	session, err := s.sessionRepo.CreateSession(ctx, user.ID, client.DeviceName, client.UserAgent, client.IPAddress, time.Now().Add(refreshTokenTTL))
+	if err != nil {
 		return "", "", err
 	}
 
-	// Generate an access token
-	accessToken, err = s.generateAccessToken(user)
+	// Create a refresh token, dropping the empty session if that fails
+	newRefreshToken, err := s.refreshTokenRepo.CreateRefreshToken(user.ID, session.ID, refreshTokenTTL)
 	if err != nil {
+		_ = s.sessionRepo.DeleteSession(ctx, session.ID)
 		return "", "", err
 	}
 
-	// Create a refresh token
-	newRefreshToken, err := s.refreshTokenRepo.CreateRefreshToken(user.ID, refreshTokenTTL)
+	// Generate an access token
+	accessToken, err = s.generateAccessToken(user, session.ID)
 	if err != nil {
 		return "", "", err
 	}
@@ -178,12 +383,27 @@ func (s *AuthService) LoginWithRefresh(email, password string, refreshTokenTTL t
 	return accessToken, newRefreshToken.Token, nil
 }
 
-// RefreshAccessToken creates a new Access Token using a Refresh Token
-func (s *AuthService) RefreshAccessToken(oldRefreshTokenString string, refreshTTL time.Duration) (string, string, error) {
+// RefreshAccessToken creates a new Access Token using a Refresh Token. The
+// refresh token is rotated within its session. Unknown, revoked and reused
+// tokens fail with ErrInvalidToken, expired ones with ErrExpiredToken.
+func (s *AuthService) RefreshAccessToken(oldRefreshTokenString string, refreshTTL time.Duration, client ClientInfo) (string, string, error) {
+	client = client.normalize()
+
 	// Rotate the refresh token
-	newToken, err := s.refreshTokenRepo.RotateRefreshToken(oldRefreshTokenString, refreshTTL)
+	newToken, err := s.refreshTokenRepo.RotateRefreshToken(oldRefreshTokenString, refreshTTL, client.UserAgent, client.IPAddress)
 	if err != nil {
-		return "", "", ErrInvalidToken
+		switch {
+		case errors.Is(err, models.ErrRefreshTokenExpired):
+			return "", "", ErrExpiredToken
+		case errors.Is(err, models.ErrRefreshTokenReused):
+			s.recordTokenReuse(oldRefreshTokenString, client)
+			return "", "", ErrInvalidToken
+		case errors.Is(err, sql.ErrNoRows),
+			errors.Is(err, models.ErrSessionRevoked),
+			errors.Is(err, models.ErrRefreshTokenRevoked):
+			return "", "", ErrInvalidToken
+		}
+		return "", "", err
 	}
 
 	// Get the user
@@ -193,7 +413,7 @@ func (s *AuthService) RefreshAccessToken(oldRefreshTokenString string, refreshTT
 	}
 
 	// Generate a new access token
-	accessToken, err := s.generateAccessToken(user)
+	accessToken, err := s.generateAccessToken(user, newToken.SessionID.UUID)
 	if err != nil {
 		return "", "", err
 	}
@@ -201,6 +421,21 @@ func (s *AuthService) RefreshAccessToken(oldRefreshTokenString string, refreshTT
 	return accessToken, newToken.Token, nil
 }
 
+// recordTokenReuse records a security event for a reused refresh token. Its
+// family was already revoked, so failing to record the event only loses the audit trail.
+func (s *AuthService) recordTokenReuse(tokenString string, client ClientInfo) {
+	token, err := s.refreshTokenRepo.GetRefreshToken(tokenString)
+	if err != nil {
+		log.Printf("refresh token reuse: look up token: %v", err)
+		return
+	}
+
+	log.Printf("Refresh token reuse detected for user %s, revoked token family %s", token.UserID, token.FamilyID)
+
+	s.recordSecurityEvent(context.Background(), token.UserID, models.SecurityEventRefreshTokenReuse, token.SessionID.UUID, client)
+}
+
+// Logout ends the session the refresh token belongs to
 func (s *AuthService) Logout(refreshTokenString string) error {
 	token, err := s.refreshTokenRepo.GetRefreshToken(refreshTokenString)
 	if err != nil {
@@ -211,5 +446,13 @@ func (s *AuthService) Logout(refreshTokenString string) error {
 		return nil
 	}
 
+	if token.SessionID.Valid {
+		err := s.sessionRepo.RevokeSession(context.Background(), token.UserID, token.SessionID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return nil
+	}
+
 	return s.refreshTokenRepo.RevokeRefreshToken(refreshTokenString)
 }
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Limits on client supplied session details
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 512
+)
+
+// ClientInfo describes the device a session is used from
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// normalize trims the client details to a sane size
+func (c ClientInfo) normalize() ClientInfo {
+	c.DeviceName = truncate(strings.TrimSpace(c.DeviceName), maxDeviceNameLength)
+	c.UserAgent = truncate(c.UserAgent, maxUserAgentLength)
+
+	return c
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Don't cut a multi-byte character in half
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// ListSessions retrieves the user's active sessions, most recently used first
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	return s.sessionRepo.ListActiveSessions(ctx, userID)
+}
+
+// RevokeSession signs one of the user's sessions out. Its refresh tokens stop
+// working right away, access tokens already issued to it run until they expire.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session,
+// returning how many sessions were revoked
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) (int64, error) {
+	return s.sessionRepo.RevokeOtherSessions(ctx, userID, currentSessionID)
+}
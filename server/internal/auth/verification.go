@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailAlreadyVerified  = errors.New("email already verified")
+	ErrVerificationThrottled = errors.New("verification email sent recently")
+)
+
+const (
+	// Used when Config.EmailVerificationTTL is not set
+	defaultEmailVerificationTTL = 24 * time.Hour
+
+	// A new verification email can be requested this long after the previous one
+	verificationResendInterval = time.Minute
+)
+
+// VerifyEmail marks the email address a verification token was sent to as
+// verified. A pending email replaces the user's current one at this point.
+// Tokens work once, and not at all once the user moved to another address.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	digest, ok := s.verifyUserToken(models.UserTokenEmailVerification, token)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	userToken, err := s.userTokenRepo.ConsumeUserToken(ctx, models.UserTokenEmailVerification, digest)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	verified, err := s.userRepo.VerifyEmail(ctx, userToken.UserID, userToken.Email)
+	if err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			return ErrEmailInUse
+		}
+		return err
+	}
+	if !verified {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// ResendVerificationEmail emails the user a new verification link, replacing
+// the previous one. It goes to the pending email when there is one.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	email := user.Email
+	if user.PendingEmail.Valid {
+		email = user.PendingEmail.String
+	} else if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	last, err := s.userTokenRepo.GetLatestUserToken(ctx, user.ID, models.UserTokenEmailVerification)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if last != nil && time.Since(last.CreatedAt) < verificationResendInterval {
+		return ErrVerificationThrottled
+	}
+
+	return s.sendVerificationEmail(ctx, user, email)
+}
+
+// sendVerificationEmail issues a verification token for email, the user's
+// current or pending one, and emails it there
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User, email string) error {
+	ttl := s.emailVerificationTTL
+	if ttl <= 0 {
+		ttl = defaultEmailVerificationTTL
+	}
+
+	token, digest, err := s.newUserToken(models.UserTokenEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.userTokenRepo.CreateUserToken(ctx, user.ID, models.UserTokenEmailVerification, digest, email, time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	link := s.appBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm this is your email address by opening the link below:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s. If you didn't create an account, you can ignore this email.\n",
+			user.Username, link, humanizeDuration(ttl)),
+	})
+}
@@ -2,19 +2,94 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// Environment is "development" or "production", the default
+	Environment     string
 	DBDSN           string
 	JWTSecret       string
 	Port            string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
 	CookieSecure    bool
+	ShutdownTimeout time.Duration
+
+	// Feed fetch scheduler
+	FetchInterval   time.Duration
+	FetchPoolSize   int
+	FetchQueueDepth int
+	FetchTimeout    time.Duration
+
+	// Background jobs
+	JobConcurrency  int
+	JobPollInterval time.Duration
+
+	// Article retention, 0 keeps articles forever
+	ArticleRetentionDays int
+	ArticleMaxPerFeed    int
+	ArticlePruneInterval time.Duration
+	ArticlePruneBatch    int
+
+	// Expired refresh tokens are purged in batches on this interval
+	RefreshTokenCleanupInterval time.Duration
+	RefreshTokenCleanupBatch    int
+
+	// Feeds without subscribers are deleted after this long, 0 keeps them
+	OrphanFeedGrace      time.Duration
+	OrphanFeedGCInterval time.Duration
+
+	// MetricsAddr serves /metrics on a separate listener, by default only on
+	// loopback. Set it to e.g. ":9090" for scrapers on other hosts, or to "off"
+	// to disable metrics. The API listener never serves /metrics.
+	MetricsAddr string
+
+	// AppBaseURL is the frontend that links in emails point to
+	AppBaseURL           string
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+
+	// SecretKey encrypts secrets such as TOTP keys and queued emails at rest, base64 encoded 32 bytes.
+	// Derived from JWTSecret when empty.
+	SecretKey string
+	// AdminRequireMFA only lets administrators with two-factor authentication use the admin API
+	AdminRequireMFA bool
+
+	// Single sign-on with an OpenID Connect provider, off while OIDCIssuerURL is empty.
+	// OIDCRedirectURL is our callback, registered with the provider.
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCAutoProvision bool
+
+	// Outgoing email, MailDriver is "smtp", "file" or "log". The log driver
+	// prints links to stdout, so it is the default and allowed only in development.
+	// Elsewhere emails are dropped with a warning until a driver is set.
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header is
+	// believed, as addresses or CIDR ranges. Empty uses the peer address.
+	TrustedProxies []netip.Prefix
+
+	// AdminEmails are promoted to admin on startup once their email is verified,
+	// as long as there is no admin yet, which bootstraps the first admin. Later
+	// changes of roles, including demotions, are left alone.
+	AdminEmails []string
 }
 
 // LoadEnv() loads environment variables from the .env file
@@ -36,14 +111,66 @@ func LoadEnv() *Config {
 	accessTTL := 15 * time.Minute
 	refreshTTL := 7 * 24 * time.Hour
 	cookieSecure := true
+	shutdownTimeout := 30 * time.Second
 
 	cfg := &Config{
+		Environment:     getEnv("APP_ENV", "production"),
 		DBDSN:           os.Getenv("DB_DSN"),
 		JWTSecret:       os.Getenv("JWT_SECRET"),
 		Port:            os.Getenv("PORT"),
 		AccessTokenTTL:  accessTTL,
 		RefreshTokenTTL: refreshTTL,
 		CookieSecure:    cookieSecure,
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", shutdownTimeout),
+		FetchInterval:   getEnvDuration("FETCH_INTERVAL", 10*time.Second),
+		FetchPoolSize:   getEnvInt("FETCH_POOL_SIZE", 10),
+		FetchQueueDepth: getEnvInt("FETCH_QUEUE_DEPTH", 10),
+		FetchTimeout:    getEnvDuration("FETCH_TIMEOUT", 15*time.Second),
+		JobConcurrency:  getEnvInt("JOB_CONCURRENCY", 4),
+		JobPollInterval: getEnvDuration("JOB_POLL_INTERVAL", time.Second),
+		AdminEmails:     getEnvList("ADMIN_EMAILS"),
+		MetricsAddr:     getEnv("METRICS_ADDR", "localhost:9090"),
+
+		ArticleRetentionDays: getEnvInt("ARTICLE_RETENTION_DAYS", 0),
+		ArticleMaxPerFeed:    getEnvInt("ARTICLE_MAX_PER_FEED", 0),
+		ArticlePruneInterval: getEnvDuration("ARTICLE_PRUNE_INTERVAL", time.Hour),
+		ArticlePruneBatch:    getEnvInt("ARTICLE_PRUNE_BATCH_SIZE", 1000),
+
+		OrphanFeedGrace:      getEnvDuration("ORPHAN_FEED_GRACE", 0),
+		OrphanFeedGCInterval: getEnvDuration("ORPHAN_FEED_GC_INTERVAL", 6*time.Hour),
+
+		RefreshTokenCleanupInterval: getEnvDuration("REFRESH_TOKEN_CLEANUP_INTERVAL", time.Hour),
+		RefreshTokenCleanupBatch:    getEnvInt("REFRESH_TOKEN_CLEANUP_BATCH_SIZE", 1000),
+
+		AppBaseURL:           getEnv("APP_BASE_URL", "http://localhost:5173"),
+		EmailVerificationTTL: getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		PasswordResetTTL:     getEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+
+		SecretKey:       os.Getenv("SECRET_KEY"),
+		AdminRequireMFA: getEnvBool("ADMIN_REQUIRE_MFA", false),
+
+		OIDCIssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:        getEnvList("OIDC_SCOPES"),
+		OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", false),
+
+		MailDriver:   os.Getenv("MAIL_DRIVER"),
+		MailFrom:     getEnv("MAIL_FROM", "Swayamsevak <no-reply@localhost>"),
+		MailDir:      getEnv("MAIL_DIR", "mail"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+	}
+
+	for _, proxy := range getEnvList("TRUSTED_PROXIES") {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES entry %q: %v", proxy, err)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
 	}
 
 	// Default port
@@ -51,5 +178,98 @@ func LoadEnv() *Config {
 		cfg.Port = "8080"
 	}
 
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = "http://localhost:" + cfg.Port + "/api/auth/oidc/callback"
+	}
+	if len(cfg.OIDCScopes) == 0 {
+		cfg.OIDCScopes = []string{"openid", "email", "profile"}
+	}
+
 	return cfg
 }
+
+// getEnv reads a string from the environment, falling back to def when unset or empty
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvBool parses a boolean such as "true" or "0" from the environment, falling back to def
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean %q for %s, using default %v", value, key, def)
+		return def
+	}
+
+	return b
+}
+
+// getEnvDuration parses a duration such as "30s" from the environment, falling back to def
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %v", value, key, def)
+		return def
+	}
+
+	return d
+}
+
+// getEnvInt parses a positive integer from the environment, falling back to def
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("Invalid value %q for %s, using default %d", value, key, def)
+		return def
+	}
+
+	return n
+}
+
+// getEnvList splits a comma separated environment variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// parsePrefix parses a CIDR range or a single address
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
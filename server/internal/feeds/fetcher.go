@@ -3,6 +3,8 @@ package feeds
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -13,39 +15,97 @@ var (
 	ErrFeedUnavailable = errors.New("feed unavailable")
 )
 
+// Used when NewFetcher is given no timeout
+const defaultFetchTimeout = 15 * time.Second
+
 type Fetcher struct {
-	parser *gofeed.Parser
-	client *http.Client
+	parser  *gofeed.Parser
+	client  *http.Client
+	timeout time.Duration
 }
 
-func NewFetcher() *Fetcher {
-	client := &http.Client{
-		Timeout: 15 * time.Second,
+// NewFetcher creates a fetcher that gives up on a feed after timeout. The timeout
+// only covers the HTTP request and parsing, storing the articles isn't bounded by it.
+func NewFetcher(timeout time.Duration) *Fetcher {
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
 	}
 
+	// Timeouts come from the request context, bounded by the fetcher's own
+	client := &http.Client{}
+
 	parser := gofeed.NewParser()
 	parser.Client = client
 	parser.UserAgent = "Swayamsevak/1.0 (+https://github.com/Harshitttttttt/Swayamsevak)"
 
 	return &Fetcher{
-		parser: parser,
-		client: client,
+		parser:  parser,
+		client:  client,
+		timeout: timeout,
 	}
 }
 
 // Fetch receives and parses a feed from the given URL.
 func (f *Fetcher) Fetch(ctx context.Context, feedURL string) ([]*gofeed.Item, error) {
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	feed, err := f.FetchFeed(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(feed.Items) == 0 {
+		return nil, nil
+	}
+
+	return feed.Items, nil
+}
+
+// FetchFeed receives and parses a feed along with its metadata (title, link, description).
+func (f *Fetcher) FetchFeed(ctx context.Context, feedURL string) (*gofeed.Feed, error) {
+	// An earlier deadline of the caller still wins
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
 	defer cancel()
 
 	feed, err := f.parser.ParseURLWithContext(feedURL, ctx)
 	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFeedUnavailable, err)
+	}
+	if feed == nil {
 		return nil, ErrFeedUnavailable
 	}
 
-	if feed == nil || len(feed.Items) == 0 {
-		return nil, nil
-	}
+	return feed, nil
+}
 
-	return feed.Items, nil
+// FetchErrorClass buckets a fetch error into a small set of classes suitable as
+// a metric label: success, timeout, canceled, dns, network, http_4xx, http_5xx,
+// parse or store.
+func FetchErrorClass(err error) string {
+	var httpErr gofeed.HTTPError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &httpErr):
+		if httpErr.StatusCode >= 500 {
+			return "http_5xx"
+		}
+		return "http_4xx"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.Is(err, gofeed.ErrFeedTypeNotDetected):
+		return "parse"
+	case errors.Is(err, ErrFeedUnavailable):
+		// Anything else the parser rejected, e.g. malformed XML
+		return "parse"
+	default:
+		return "store"
+	}
 }
@@ -0,0 +1,151 @@
+package feeds
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sort"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// MergeGroup describes a set of feeds that share a URL key and were merged into one
+type MergeGroup struct {
+	URLKey     string
+	KeptID     uuid.UUID
+	KeptURL    string
+	MergedIDs  []uuid.UUID
+	MergedURLs []string
+}
+
+// MergeReport summarizes a MergeDuplicateFeeds run
+type MergeReport struct {
+	Groups             []MergeGroup
+	KeysUpdated        int
+	InvalidURLs        []string
+	MovedSubscriptions int64
+	MovedArticles      int64
+}
+
+// MergeDuplicateFeeds backfills the normalized URL and key of every feed and
+// consolidates feeds whose URLs normalize to the same key. Subscriptions and
+// articles of the duplicates are moved to the kept feed. With dryRun set the
+// report is computed without changing anything.
+func (s *FeedService) MergeDuplicateFeeds(ctx context.Context, dryRun bool) (*MergeReport, error) {
+	infos, err := s.feedRepo.ListFeedURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MergeReport{}
+
+	// Group feeds by key, keeping the order of first appearance (oldest first)
+	groups := make(map[string][]*models.FeedURLInfo)
+	normalized := make(map[uuid.UUID]string)
+	keys := make([]string, 0)
+	for _, info := range infos {
+		feedURL, err := NormalizeFeedURL(info.FeedURL)
+		if err != nil {
+			report.InvalidURLs = append(report.InvalidURLs, info.FeedURL)
+			continue
+		}
+
+		key := FeedURLKey(feedURL)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], info)
+		normalized[info.ID] = feedURL
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		keep := pickFeedToKeep(group)
+
+		if len(group) == 1 {
+			if keep.URLKey.Valid && keep.URLKey.String == key && keep.FeedURL == normalized[keep.ID] {
+				continue
+			}
+
+			report.KeysUpdated++
+			if dryRun {
+				continue
+			}
+
+			if err := s.feedRepo.UpdateFeedURL(ctx, keep.ID, normalized[keep.ID], key); err != nil {
+				return report, err
+			}
+			continue
+		}
+
+		mergeGroup := MergeGroup{
+			URLKey:  key,
+			KeptID:  keep.ID,
+			KeptURL: normalized[keep.ID],
+		}
+		for _, info := range group {
+			if info.ID == keep.ID {
+				continue
+			}
+			mergeGroup.MergedIDs = append(mergeGroup.MergedIDs, info.ID)
+			mergeGroup.MergedURLs = append(mergeGroup.MergedURLs, info.FeedURL)
+		}
+
+		if !dryRun {
+			subscriptions, articles, err := s.feedRepo.MergeFeeds(ctx, keep.ID, mergeGroup.MergedIDs, mergeGroup.KeptURL, key)
+			if err != nil {
+				return report, err
+			}
+
+			report.MovedSubscriptions += subscriptions
+			report.MovedArticles += articles
+			log.Printf("merged %d duplicate feeds into %s (%s)", len(mergeGroup.MergedIDs), keep.ID, mergeGroup.KeptURL)
+		}
+
+		report.Groups = append(report.Groups, mergeGroup)
+	}
+
+	return report, nil
+}
+
+// BackfillURLKeys gives feeds from before url_key existed their key, merging
+// the ones that turn out to be duplicates, so GetFeedByURL matches every
+// variant of their URL. It runs on startup and does nothing once every feed has
+// a key; instances starting together take turns.
+func (s *FeedService) BackfillURLKeys(ctx context.Context) (*MergeReport, error) {
+	unlock, err := s.feedRepo.LockFeedMerge(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	missing, err := s.feedRepo.CountFeedsWithoutURLKey(ctx)
+	if err != nil || missing == 0 {
+		return &MergeReport{}, err
+	}
+
+	return s.MergeDuplicateFeeds(ctx, false)
+}
+
+// pickFeedToKeep prefers https feeds, then the feed with the most subscribers, then the oldest
+func pickFeedToKeep(group []*models.FeedURLInfo) *models.FeedURLInfo {
+	candidates := append([]*models.FeedURLInfo(nil), group...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iHTTPS, jHTTPS := isHTTPS(candidates[i].FeedURL), isHTTPS(candidates[j].FeedURL)
+		if iHTTPS != jHTTPS {
+			return iHTTPS
+		}
+		if candidates[i].Subscribers != candidates[j].Subscribers {
+			return candidates[i].Subscribers > candidates[j].Subscribers
+		}
+		return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+	})
+
+	return candidates[0]
+}
+
+func isHTTPS(feedURL string) bool {
+	u, err := url.Parse(feedURL)
+	return err == nil && u.Scheme == "https"
+}
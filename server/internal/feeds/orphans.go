@@ -0,0 +1,79 @@
+package feeds
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+)
+
+// KindCollectOrphanFeeds deletes feeds that have had no subscribers for a while
+const KindCollectOrphanFeeds = "feeds.collect_orphans"
+
+// Orphaned feeds are deleted this many at a time, each one cascades to its articles
+const orphanDeleteBatchSize = 50
+
+// CollectOrphansPayload configures the feeds.collect_orphans job
+type CollectOrphansPayload struct {
+	// Grace is how long a feed must have been without subscribers, e.g. "720h"
+	Grace string `json:"grace"`
+}
+
+// OrphanReport summarizes a CollectOrphanFeeds run
+type OrphanReport struct {
+	Orphaned int64
+	Revived  int64
+	Deleted  int64
+}
+
+// CollectOrphanFeeds marks feeds that lost their last subscriber and deletes the
+// ones that stayed without subscribers for longer than grace. A feed is only
+// marked when the collector runs, so the effective grace period is up to one
+// collector interval longer than grace.
+func (s *FeedService) CollectOrphanFeeds(ctx context.Context, grace time.Duration) (*OrphanReport, error) {
+	report := &OrphanReport{}
+
+	orphaned, revived, err := s.feedRepo.MarkOrphanedFeeds(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Orphaned, report.Revived = orphaned, revived
+
+	cutoff := time.Now().Add(-grace)
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		deleted, err := s.feedRepo.DeleteOrphanedFeeds(ctx, cutoff, orphanDeleteBatchSize)
+		if err != nil {
+			return report, err
+		}
+		report.Deleted += deleted
+
+		if deleted < orphanDeleteBatchSize {
+			return report, nil
+		}
+	}
+}
+
+// RegisterOrphanCollector registers the recurring feeds.collect_orphans job
+func (s *FeedService) RegisterOrphanCollector(jobService *jobs.JobService, interval, grace time.Duration) {
+	jobs.Register(jobService, KindCollectOrphanFeeds, func(ctx context.Context, payload CollectOrphansPayload) error {
+		grace, err := time.ParseDuration(payload.Grace)
+		if err != nil {
+			return jobs.Permanent(err)
+		}
+
+		report, err := s.CollectOrphanFeeds(ctx, grace)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Orphan feeds: %d newly orphaned, %d resubscribed, %d deleted", report.Orphaned, report.Revived, report.Deleted)
+		return nil
+	})
+
+	jobService.Every(KindCollectOrphanFeeds, interval, CollectOrphansPayload{Grace: grace.String()})
+}
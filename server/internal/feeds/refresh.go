@@ -0,0 +1,325 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshJobNotFound  = errors.New("refresh job not found")
+	ErrRefreshRateLimited  = errors.New("too many refresh requests")
+	ErrNotSubscribedToFeed = errors.New("not subscribed to feed")
+)
+
+// KindRefreshFeeds fetches feeds a user asked to refresh right away
+const KindRefreshFeeds = "feeds.refresh"
+
+const (
+	// Each user may start refreshRateLimit refreshes per refreshRateWindow
+	refreshRateLimit  = 5
+	refreshRateWindow = time.Minute
+
+	// Number of feeds fetched at once when refreshing all subscriptions
+	refreshConcurrency = 4
+)
+
+type RefreshStatus string
+
+const (
+	RefreshStatusPending   RefreshStatus = "pending"
+	RefreshStatusRunning   RefreshStatus = "running"
+	RefreshStatusCompleted RefreshStatus = "completed"
+	RefreshStatusFailed    RefreshStatus = "failed"
+)
+
+// FeedRefreshResult is the outcome of refreshing a single feed
+type FeedRefreshResult struct {
+	FeedID      uuid.UUID `json:"feed_id"`
+	FeedURL     string    `json:"feed_url"`
+	NewArticles int64     `json:"new_articles"`
+	// Error is one of a fixed set of messages, never the raw error
+	Error string `json:"error,omitempty"`
+}
+
+// RefreshJob tracks an on-demand refresh requested by a user
+type RefreshJob struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      RefreshStatus
+	Total       int
+	NewArticles int64
+	Results     []FeedRefreshResult
+	CreatedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// refreshPayload is the payload of a feeds.refresh job
+type refreshPayload struct {
+	UserID  uuid.UUID   `json:"user_id"`
+	FeedIDs []uuid.UUID `json:"feed_ids"`
+}
+
+// refreshResult is the result a feeds.refresh job reports, it grows as feeds finish
+type refreshResult struct {
+	NewArticles int64               `json:"new_articles"`
+	Results     []FeedRefreshResult `json:"results"`
+}
+
+// RefreshService runs on-demand feed refreshes as background jobs, so their
+// status and rate limits are shared by every instance. Fetches go through
+// FeedService.FetchAndStoreFeedAs, so a feed that is already being fetched isn't
+// fetched twice.
+type RefreshService struct {
+	feedService *FeedService
+	jobService  *jobs.JobService
+}
+
+// NewRefreshService creates a new refresh service and registers the feeds.refresh job
+func NewRefreshService(feedService *FeedService, jobService *jobs.JobService) *RefreshService {
+	s := &RefreshService{
+		feedService: feedService,
+		jobService:  jobService,
+	}
+
+	jobService.Handle(KindRefreshFeeds, s.run)
+
+	return s
+}
+
+// RefreshFeed enqueues an immediate fetch of a feed the user is subscribed to
+func (s *RefreshService) RefreshFeed(ctx context.Context, userID, feedID uuid.UUID) (*RefreshJob, error) {
+	subscribed, err := s.feedService.IsSubscribed(userID, feedID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscribed {
+		return nil, ErrNotSubscribedToFeed
+	}
+
+	if _, err := s.feedService.GetFeedByID(feedID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFeedNotFound
+		}
+		return nil, err
+	}
+
+	return s.start(ctx, userID, []uuid.UUID{feedID})
+}
+
+// RefreshSubscriptions enqueues an immediate fetch of every feed the user is subscribed to
+func (s *RefreshService) RefreshSubscriptions(ctx context.Context, userID uuid.UUID) (*RefreshJob, error) {
+	subscribedFeeds, err := s.feedService.GetUserSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	feedIDs := make([]uuid.UUID, 0, len(subscribedFeeds))
+	for _, sf := range subscribedFeeds {
+		feedIDs = append(feedIDs, sf.Feed.ID)
+	}
+
+	return s.start(ctx, userID, feedIDs)
+}
+
+// GetJob returns the state of a refresh job owned by the user
+func (s *RefreshService) GetJob(ctx context.Context, userID, jobID uuid.UUID) (*RefreshJob, error) {
+	job, err := s.jobService.GetUserJob(ctx, userID, KindRefreshFeeds, jobID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			return nil, ErrRefreshJobNotFound
+		}
+		return nil, err
+	}
+
+	return newRefreshJob(job)
+}
+
+func (s *RefreshService) start(ctx context.Context, userID uuid.UUID, feedIDs []uuid.UUID) (*RefreshJob, error) {
+	payload := refreshPayload{
+		UserID:  userID,
+		FeedIDs: feedIDs,
+	}
+
+	// Failed fetches are reported in the result, retrying the whole refresh won't help
+	job, err := s.jobService.EnqueueForUser(ctx, userID, KindRefreshFeeds, payload, 1, refreshRateLimit, refreshRateWindow)
+	if err != nil {
+		if errors.Is(err, jobs.ErrRateLimited) {
+			return nil, ErrRefreshRateLimited
+		}
+		return nil, err
+	}
+
+	return newRefreshJob(job)
+}
+
+// run is the feeds.refresh job handler, it records the result after every feed
+func (s *RefreshService) run(ctx context.Context, job *models.Job) error {
+	var payload refreshPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	result := refreshResult{
+		Results: make([]FeedRefreshResult, 0, len(payload.FeedIDs)),
+	}
+
+	// Feeds are leased to this run rather than the instance, so a feed the
+	// scheduler already queued here isn't fetched a second time
+	owner := "refresh-" + job.ID.String()
+
+	sem := make(chan struct{}, refreshConcurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, feedID := range payload.FeedIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(feedID uuid.UUID) {
+			defer wg.Done()
+			defer func() {
+				<-sem
+			}()
+
+			// Every fetch ends within a feed lease, so keeping the job leased
+			// that long past the latest start covers the fetches in flight
+			if err := jobs.ExtendLease(ctx, feedLeaseTTL); err != nil && ctx.Err() == nil {
+				log.Printf("refresh: failed to extend lease of job %s: %v", job.ID, err)
+			}
+
+			feedResult := s.refreshOne(ctx, feedID, owner)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			result.Results = append(result.Results, feedResult)
+			result.NewArticles += feedResult.NewArticles
+			if err := s.jobService.SetResult(ctx, job.ID, result); err != nil {
+				log.Printf("refresh: failed to record progress of job %s: %v", job.ID, err)
+			}
+		}(feedID)
+	}
+
+	wg.Wait()
+
+	return s.jobService.SetResult(context.WithoutCancel(ctx), job.ID, result)
+}
+
+// refreshOne fetches a single feed of a refresh job as owner. A feed that is
+// already being fetched counts as refreshed, its new articles arrive with that fetch.
+func (s *RefreshService) refreshOne(ctx context.Context, feedID uuid.UUID, owner string) FeedRefreshResult {
+	result := FeedRefreshResult{
+		FeedID: feedID,
+	}
+
+	feed, err := s.feedService.GetFeedByID(feedID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("refresh: failed to load feed %s: %v", feedID, err)
+		}
+		result.Error = refreshErrorMessage(err)
+		return result
+	}
+	result.FeedURL = feed.FeedURL
+
+	inserted, err := s.feedService.FetchAndStoreFeedAs(ctx, feed, owner)
+	if errors.Is(err, ErrFetchInProgress) {
+		return result
+	}
+	if err != nil {
+		log.Printf("refresh: failed to fetch feed %s: %v", feed.FeedURL, err)
+		result.Error = refreshErrorMessage(err)
+	}
+	result.NewArticles = inserted
+
+	return result
+}
+
+// refreshErrorMessage maps a fetch error to the message shown to users, raw
+// errors may leak internal addresses or database details
+func refreshErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "feed not found"
+	}
+
+	switch FetchErrorClass(err) {
+	case "timeout":
+		return "feed timed out"
+	case "canceled":
+		return "refresh was cancelled"
+	case "http_4xx":
+		return "feed returned a client error"
+	case "http_5xx":
+		return "feed server returned an error"
+	case "dns", "network":
+		return "feed could not be reached"
+	case "parse":
+		return "feed could not be parsed"
+	default:
+		return "articles could not be stored"
+	}
+}
+
+// newRefreshJob builds the state of a refresh from its job. A refresh only
+// fails when none of its feeds could be refreshed.
+func newRefreshJob(job *models.Job) (*RefreshJob, error) {
+	var payload refreshPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	var result refreshResult
+	if job.Result != nil {
+		if err := json.Unmarshal(job.Result, &result); err != nil {
+			return nil, err
+		}
+	}
+
+	refresh := &RefreshJob{
+		ID:          job.ID,
+		UserID:      payload.UserID,
+		Total:       len(payload.FeedIDs),
+		NewArticles: result.NewArticles,
+		Results:     result.Results,
+		CreatedAt:   job.CreatedAt,
+	}
+	if refresh.Results == nil {
+		refresh.Results = make([]FeedRefreshResult, 0)
+	}
+	if job.FinishedAt.Valid {
+		refresh.FinishedAt = job.FinishedAt.Time
+	}
+
+	switch job.Status {
+	case models.JobStatusPending:
+		refresh.Status = RefreshStatusPending
+	case models.JobStatusRunning:
+		refresh.Status = RefreshStatusRunning
+	case models.JobStatusDead:
+		refresh.Status = RefreshStatusFailed
+	default:
+		refresh.Status = RefreshStatusCompleted
+
+		failed := 0
+		for _, r := range refresh.Results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 && failed == len(refresh.Results) {
+			refresh.Status = RefreshStatusFailed
+		}
+	}
+
+	return refresh, nil
+}
@@ -0,0 +1,176 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRetention = errors.New("retention values must not be negative")
+)
+
+// KindPruneArticles deletes articles that fall outside the retention policy
+const KindPruneArticles = "articles.prune"
+
+// Articles are deleted this many at a time unless the policy says otherwise
+const defaultPruneBatchSize = 1000
+
+// RetentionPolicy is the global article retention default. Feeds can override
+// either limit, 0 disables it.
+type RetentionPolicy struct {
+	// MaxAgeDays deletes articles stored longer than this many days. Age is
+	// measured from when the article was stored, not published, so articles
+	// still present in a feed aren't deleted and re-inserted on every fetch.
+	MaxAgeDays int `json:"max_age_days"`
+	// MaxArticles keeps only the newest MaxArticles articles of each feed
+	MaxArticles int `json:"max_articles"`
+	// BatchSize is how many articles are deleted per statement
+	BatchSize int `json:"batch_size"`
+}
+
+// PruneReport summarizes a PruneArticles run
+type PruneReport struct {
+	Expired   int64
+	OverLimit int64
+	Batches   int
+	Duration  time.Duration
+}
+
+// Total is the number of articles deleted
+func (r *PruneReport) Total() int64 {
+	return r.Expired + r.OverLimit
+}
+
+// PruneArticles deletes articles outside the retention policy. Deletes run in
+// small batches, each its own statement, so no lock is held for long and
+// concurrent inserts aren't blocked. Articles any user starred, saved or
+// annotated are always kept, pruned articles aren't inserted again.
+func (s *FeedService) PruneArticles(ctx context.Context, policy RetentionPolicy) (*PruneReport, error) {
+	batchSize := policy.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPruneBatchSize
+	}
+
+	report := &PruneReport{}
+	start := time.Now()
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
+	// Age first, so the per-feed limit doesn't count articles that are expiring anyway
+	expired, batches, err := pruneInBatches(ctx, batchSize, func(limit int) (int64, error) {
+		return s.articleRepo.DeleteExpiredArticles(ctx, policy.MaxAgeDays, limit)
+	})
+	report.Expired, report.Batches = expired, batches
+	if err != nil {
+		return report, err
+	}
+
+	// Feeds are counted once per run, then only the articles of a feed over its
+	// limit are ranked
+	limits, err := s.articleRepo.GetFeedsOverArticleLimit(ctx, policy.MaxArticles)
+	if err != nil {
+		return report, err
+	}
+
+	for _, feedLimit := range limits {
+		overLimit, batches, err := pruneInBatches(ctx, batchSize, func(limit int) (int64, error) {
+			return s.articleRepo.DeleteFeedArticlesOverLimit(ctx, feedLimit.FeedID, feedLimit.MaxArticles, limit)
+		})
+		report.OverLimit += overLimit
+		report.Batches += batches
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// pruneInBatches calls deleteBatch until it deletes less than a full batch
+func pruneInBatches(ctx context.Context, batchSize int, deleteBatch func(limit int) (int64, error)) (deleted int64, batches int, err error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, batches, err
+		}
+
+		n, err := deleteBatch(batchSize)
+		if err != nil {
+			return deleted, batches, err
+		}
+
+		deleted += n
+		batches++
+
+		if n < int64(batchSize) {
+			return deleted, batches, nil
+		}
+	}
+}
+
+// GetFeedRetention returns the retention overrides of a feed
+func (s *FeedService) GetFeedRetention(ctx context.Context, feedID uuid.UUID) (*models.FeedRetention, error) {
+	retention, err := s.feedRepo.GetFeedRetention(ctx, feedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFeedNotFound
+		}
+		return nil, err
+	}
+
+	return retention, nil
+}
+
+// SetFeedRetention overrides the global retention policy for a feed. A nil
+// value falls back to the global default, 0 disables that limit for the feed.
+func (s *FeedService) SetFeedRetention(ctx context.Context, feedID uuid.UUID, days, maxArticles *int) (*models.FeedRetention, error) {
+	retention := &models.FeedRetention{
+		FeedID: feedID,
+	}
+
+	if days != nil {
+		if *days < 0 {
+			return nil, ErrInvalidRetention
+		}
+		retention.Days = sql.NullInt32{Int32: int32(*days), Valid: true}
+	}
+	if maxArticles != nil {
+		if *maxArticles < 0 {
+			return nil, ErrInvalidRetention
+		}
+		retention.MaxArticles = sql.NullInt32{Int32: int32(*maxArticles), Valid: true}
+	}
+
+	if err := s.feedRepo.UpdateFeedRetention(ctx, retention); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFeedNotFound
+		}
+		return nil, err
+	}
+
+	return retention, nil
+}
+
+// RegisterPruneJob registers the recurring articles.prune job with the global policy
+func (s *FeedService) RegisterPruneJob(jobService *jobs.JobService, interval time.Duration, policy RetentionPolicy) {
+	jobs.Register(jobService, KindPruneArticles, func(ctx context.Context, policy RetentionPolicy) error {
+		report, err := s.PruneArticles(ctx, policy)
+		if err != nil {
+			log.Printf("Pruned %d articles before failing: %v", report.Total(), err)
+			return err
+		}
+
+		log.Printf("Pruned %d articles (%d expired, %d over the per-feed limit) in %d batches, took %s",
+			report.Total(), report.Expired, report.OverLimit, report.Batches, report.Duration.Round(time.Millisecond))
+		return nil
+	})
+
+	jobService.Every(KindPruneArticles, interval, policy)
+}
@@ -4,17 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"os"
 	"time"
 
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/metrics"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
 	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
 	ErrFeedAlreadyExists = errors.New("feed already exists")
 	ErrFeedNotFound      = errors.New("feed not found")
+	ErrFetchInProgress   = errors.New("feed is being fetched by another worker")
 )
 
+// How long a feed stays leased to a worker before other workers may reclaim it.
+// This must comfortably exceed the fetch timeout plus the time to store articles.
+const feedLeaseTTL = 2 * time.Minute
+
 // FeedService provides feed-related functionality
 type FeedService struct {
 	feedRepo             *models.FeedRepository
@@ -22,6 +32,12 @@ type FeedService struct {
 	articleRepo          *models.ArticleRepository
 
 	fetcher *Fetcher
+
+	// instanceID identifies this process as the owner of feed leases
+	instanceID string
+
+	// inflight deduplicates concurrent fetches of the same feed
+	inflight singleflight.Group
 }
 
 // NewFeedService creates a new feed service
@@ -31,13 +47,31 @@ func NewFeedService(feedRepo *models.FeedRepository, feedSubscriptionRepo *model
 		feedSubscriptionRepo: feedSubscriptionRepo,
 		articleRepo:          articleRepo,
 		fetcher:              fetcher,
+		instanceID:           newInstanceID(),
 	}
 }
 
-// AddFeed adds a new feed
+// newInstanceID builds a lease owner name that is unique per process
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
+
+// AddFeed adds a new feed. The URL is normalized first so variants of an
+// existing feed's URL are reported as ErrFeedAlreadyExists.
 func (s *FeedService) AddFeed(feedURL, siteURL, title, description string) (*models.Feed, error) {
-	// Check if the feed already exists could be added here
-	_, err := s.feedRepo.GetFeedByURL(feedURL)
+	feedURL, err := NormalizeFeedURL(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	urlKey := FeedURLKey(feedURL)
+
+	// Check if the feed already exists
+	_, err = s.feedRepo.GetFeedByURL(feedURL, urlKey)
 	if err == nil {
 		return nil, ErrFeedAlreadyExists
 	}
@@ -47,9 +81,12 @@ func (s *FeedService) AddFeed(feedURL, siteURL, title, description string) (*mod
 		return nil, err
 	}
 
-	// Create the feed
-	feed, err := s.feedRepo.CreateFeed(feedURL, siteURL, title, description)
+	// Create the feed, a concurrent request may have added it since the check
+	feed, err := s.feedRepo.CreateFeed(feedURL, urlKey, siteURL, title, description)
 	if err != nil {
+		if errors.Is(err, models.ErrFeedExists) {
+			return nil, ErrFeedAlreadyExists
+		}
 		return nil, err
 	}
 
@@ -66,9 +103,14 @@ func (s *FeedService) GetFeedByID(id uuid.UUID) (*models.Feed, error) {
 	return feed, nil
 }
 
-// GetFeedByURL retrieves a feed by its URL
+// GetFeedByURL retrieves a feed by its URL, matching any variant of the normalized URL
 func (s *FeedService) GetFeedByURL(feedURL string) (*models.Feed, error) {
-	feed, err := s.feedRepo.GetFeedByURL(feedURL)
+	feedURL, err := NormalizeFeedURL(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := s.feedRepo.GetFeedByURL(feedURL, FeedURLKey(feedURL))
 	if err != nil {
 		return nil, err
 
@@ -97,8 +139,20 @@ func (s *FeedService) ListFeeds() ([]*models.Feed, error) {
 	return feeds, nil
 }
 
-// SubscribeToFeed allows a user to subscribe to a feed
-func (s *FeedService) SubscribeToFeed(userID, feedID uuid.UUID, customTitle string) error {
+// DeleteFeed removes a feed for every user, with its subscriptions and articles
+func (s *FeedService) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	if err := s.feedRepo.DeleteFeed(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFeedNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SubscribeToFeed allows a user to subscribe to a feed, optionally placing it in a folder
+func (s *FeedService) SubscribeToFeed(userID, feedID uuid.UUID, customTitle, folder string) error {
 	// Get the feed from the db
 	feed, err := s.feedRepo.GetFeedByID(feedID)
 	if err != nil {
@@ -110,7 +164,7 @@ func (s *FeedService) SubscribeToFeed(userID, feedID uuid.UUID, customTitle stri
 		customTitle = feed.Title
 	}
 
-	_, err = s.feedSubscriptionRepo.SubscribeUserToFeed(userID, feedID, customTitle)
+	_, err = s.feedSubscriptionRepo.SubscribeUserToFeed(userID, feedID, customTitle, folder)
 	if err != nil {
 		return err
 	}
@@ -118,26 +172,152 @@ func (s *FeedService) SubscribeToFeed(userID, feedID uuid.UUID, customTitle stri
 	return nil
 }
 
-// FetchAndStoreFeed fetches a feed and stores its articles
-func (s *FeedService) FetchAndStoreFeed(ctx context.Context, feed *models.Feed) error {
-	// Claim First
-	if err := s.feedRepo.UpdateLastFetchedAt(feed.ID); err != nil {
-		return err
+// GetUserSubscriptions retrieves the user's subscriptions along with their feeds
+func (s *FeedService) GetUserSubscriptions(userID uuid.UUID) ([]*models.SubscribedFeed, error) {
+	subscribedFeeds, err := s.feedSubscriptionRepo.GetSubscribedFeedsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscribedFeeds, nil
+}
+
+// IsSubscribed reports whether a user is already subscribed to a feed
+func (s *FeedService) IsSubscribed(userID, feedID uuid.UUID) (bool, error) {
+	return s.feedSubscriptionRepo.Exists(userID, feedID)
+}
+
+// SubscribeByURL subscribes a user to the feed at rawURL in one step. The URL is
+// normalized, the feed row is found or created without racing other subscribers
+// and, when the feed has never been fetched, its articles are fetched right away.
+// feedCreated and subscribed report whether a new feed row and a new
+// subscription were created.
+func (s *FeedService) SubscribeByURL(ctx context.Context, userID uuid.UUID, rawURL, customTitle, folder string) (feed *models.Feed, feedCreated bool, subscribed bool, err error) {
+	feedURL, err := NormalizeFeedURL(rawURL)
+	if err != nil {
+		return nil, false, false, err
+	}
+	urlKey := FeedURLKey(feedURL)
+
+	feed, err = s.feedRepo.GetFeedByURL(feedURL, urlKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, false, err
 	}
 
+	if feed == nil {
+		// Unknown feed, make sure the URL actually serves a feed before storing it
+		parsed, err := s.fetcher.FetchFeed(ctx, feedURL)
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		title := sanitize(parsed.Title)
+		if title == "" {
+			title = feedURL
+		}
+
+		feed, feedCreated, err = s.feedRepo.FindOrCreateFeed(feedURL, urlKey, parsed.Link, title, sanitize(parsed.Description))
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		// Store the items we already have instead of fetching the feed twice
+		if feedCreated {
+			if err := s.feedRepo.UpdateLastFetchedAt(feed.ID); err != nil {
+				return nil, false, false, err
+			}
+
+			if _, err := s.articleRepo.InsertManyArticlesIgnoreDuplicates(ctx, NormalizeItems(parsed.Items, feed.ID)); err != nil {
+				return nil, false, false, err
+			}
+		}
+	} else if !feed.LastFetchedAt.Valid {
+		if _, err := s.FetchAndStoreFeed(ctx, feed); err != nil {
+			// The feed exists and the scheduler will retry it, don't fail the subscription
+			log.Printf("subscribe: first fetch of %s failed: %v", feed.FeedURL, err)
+		}
+	}
+
+	if customTitle == "" {
+		customTitle = feed.Title
+	}
+
+	_, subscribed, err = s.feedSubscriptionRepo.EnsureSubscription(userID, feed.ID, customTitle, folder)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	return feed, feedCreated, subscribed, nil
+}
+
+// FetchAndStoreFeed fetches a feed and stores its articles, returning how many
+// articles were new. Concurrent calls for the same feed share a single fetch.
+// The feed is leased to this instance, so feeds claimed by the worker are fetched
+// under the lease they already hold.
+func (s *FeedService) FetchAndStoreFeed(ctx context.Context, feed *models.Feed) (int64, error) {
+	return s.FetchAndStoreFeedAs(ctx, feed, s.instanceID)
+}
+
+// FetchAndStoreFeedAs is FetchAndStoreFeed with the feed leased to owner. It
+// returns ErrFetchInProgress while any other owner, including this instance's
+// worker, holds the lease.
+func (s *FeedService) FetchAndStoreFeedAs(ctx context.Context, feed *models.Feed, owner string) (int64, error) {
+	v, err, _ := s.inflight.Do(owner+"/"+feed.ID.String(), func() (any, error) {
+		return s.fetchAndStoreFeed(ctx, feed, owner)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return v.(int64), nil
+}
+
+func (s *FeedService) fetchAndStoreFeed(ctx context.Context, feed *models.Feed, owner string) (int64, error) {
+	// Claim first, owners may renew the leases they already hold
+	leased, err := s.feedRepo.LeaseFeed(ctx, feed.ID, owner, feedLeaseTTL)
+	if err != nil {
+		return 0, err
+	}
+	if !leased {
+		return 0, ErrFetchInProgress
+	}
+
+	// Release with a fresh context so the lease is given up even if ctx was cancelled
+	defer func() {
+		if err := s.feedRepo.ReleaseFeedLease(context.WithoutCancel(ctx), feed.ID, owner); err != nil {
+			log.Printf("Failed to release lease on feed %s: %v", feed.FeedURL, err)
+		}
+	}()
+
+	start := time.Now()
+	inserted, err := s.fetchItems(ctx, feed)
+	metrics.ObserveFetch(FetchErrorClass(err), time.Since(start), inserted)
+
+	return inserted, err
+}
+
+// fetchItems fetches a feed and stores its new articles. Only the fetch is
+// bounded by the fetch timeout, once the articles are in hand they are stored
+// even if ctx is cancelled meanwhile, rather than abandoning a COPY halfway.
+func (s *FeedService) fetchItems(ctx context.Context, feed *models.Feed) (int64, error) {
 	rss, err := s.fetcher.Fetch(ctx, feed.FeedURL)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	articles := NormalizeItems(rss, feed.ID)
 
-	return s.articleRepo.InsertManyArticlesIgnoreDuplicates(ctx, articles)
+	return s.articleRepo.InsertManyArticlesIgnoreDuplicates(context.WithoutCancel(ctx), articles)
+}
+
+// ReleaseFeed gives up this instance's lease on a claimed feed without fetching it
+func (s *FeedService) ReleaseFeed(ctx context.Context, feed *models.Feed) error {
+	return s.feedRepo.ReleaseFeedLease(ctx, feed.ID, s.instanceID)
 }
 
-// GetNextFeedToFetch retrieves the next feed that needs to be fetched
-func (s *FeedService) GetNextFeedsToFetch(ctx context.Context, limit int, olderThan time.Duration) ([]*models.Feed, error) {
-	feeds, err := s.feedRepo.GetNextFeedsToFetch(ctx, limit, olderThan)
+// ClaimFeedsToFetch leases up to limit feeds that are due for a fetch to this instance
+func (s *FeedService) ClaimFeedsToFetch(ctx context.Context, limit int, olderThan time.Duration) ([]*models.Feed, error) {
+	feeds, err := s.feedRepo.ClaimFeedsToFetch(ctx, s.instanceID, limit, olderThan, feedLeaseTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,60 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrArticleNotFound = errors.New("article not found")
+	ErrNoteTooLong     = errors.New("note is too long")
+)
+
+// Notes longer than this many characters are rejected
+const maxArticleNoteLength = 10000
+
+// ArticleStateChange holds the state fields to change, nil fields keep their
+// current value
+type ArticleStateChange struct {
+	Read    *bool
+	Starred *bool
+	Saved   *bool
+	Note    *string
+}
+
+// SetArticleState marks an article of one of the user's subscriptions read,
+// starred or saved, or annotates it. Starred, saved and annotated articles are
+// never pruned by retention.
+func (s *FeedService) SetArticleState(ctx context.Context, userID, articleID uuid.UUID, change ArticleStateChange) (*models.ArticleState, error) {
+	var update models.ArticleStateUpdate
+	if change.Read != nil {
+		update.Read = sql.NullBool{Bool: *change.Read, Valid: true}
+	}
+	if change.Starred != nil {
+		update.Starred = sql.NullBool{Bool: *change.Starred, Valid: true}
+	}
+	if change.Saved != nil {
+		update.Saved = sql.NullBool{Bool: *change.Saved, Valid: true}
+	}
+	if change.Note != nil {
+		if utf8.RuneCountInString(*change.Note) > maxArticleNoteLength {
+			return nil, ErrNoteTooLong
+		}
+		update.Note = sql.NullString{String: *change.Note, Valid: true}
+	}
+
+	state, err := s.articleRepo.UpsertArticleState(ctx, userID, articleID, update)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+
+	return state, nil
+}
@@ -0,0 +1,105 @@
+package feeds
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidFeedURL = errors.New("invalid feed url")
+)
+
+// NormalizeFeedURL cleans up a user supplied feed URL so the same feed is
+// always stored under the same string. URLs without a scheme default to https.
+// The scheme, path and query are otherwise kept as given since they are needed
+// to actually fetch the feed, signed URLs break when their query is re-encoded;
+// use FeedURLKey to detect duplicates.
+func NormalizeFeedURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", ErrInvalidFeedURL
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidFeedURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", ErrInvalidFeedURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", ErrInvalidFeedURL
+	}
+
+	// Drop ports that are implied by the scheme
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	// An empty query is the same as none
+	u.ForceQuery = false
+
+	// Fragments are never sent to the server
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u.String(), nil
+}
+
+// FeedURLKey derives the key used to detect duplicate feeds from a URL returned
+// by NormalizeFeedURL. It ignores the scheme, a leading "www." and trailing
+// slashes, so http://www.example.com/feed/ and https://example.com/feed share a
+// key. Query parameters are compared in any order.
+func FeedURLKey(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return feedURL
+	}
+
+	host := strings.TrimPrefix(u.Host, "www.")
+	path := strings.TrimRight(u.EscapedPath(), "/")
+
+	key := host + path
+	if params := queryParams(u.RawQuery); len(params) > 0 {
+		key += "?" + strings.Join(params, "&")
+	}
+
+	return key
+}
+
+// queryParams splits a raw query into its sorted parameters, leaving each one
+// encoded as given so "a" and "a=" stay different
+func queryParams(rawQuery string) []string {
+	params := make([]string, 0)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+
+	slices.Sort(params)
+	return params
+}
@@ -0,0 +1,97 @@
+package feeds
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeFeedURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"adds https", "example.com/feed", "https://example.com/feed"},
+		{"trims space", "  https://example.com/feed  ", "https://example.com/feed"},
+		{"lowercases scheme and host", "HTTP://Example.COM/Feed", "http://example.com/Feed"},
+		{"keeps http", "http://example.com/feed", "http://example.com/feed"},
+		{"root path", "https://example.com", "https://example.com/"},
+		{"keeps trailing slash", "https://example.com/feed/", "https://example.com/feed/"},
+		{"keeps www", "https://www.example.com/feed", "https://www.example.com/feed"},
+		{"drops https default port", "https://example.com:443/feed", "https://example.com/feed"},
+		{"drops http default port", "http://example.com:80/feed", "http://example.com/feed"},
+		{"keeps other port", "https://example.com:8443/feed", "https://example.com:8443/feed"},
+		{"keeps port of other scheme", "http://example.com:443/feed", "http://example.com:443/feed"},
+		{"trailing dot in host", "https://example.com./feed", "https://example.com/feed"},
+		{"ipv6 host", "https://[2001:DB8::1]/feed", "https://[2001:db8::1]/feed"},
+		{"ipv6 host with default port", "https://[2001:db8::1]:443/feed", "https://[2001:db8::1]/feed"},
+		{"ipv6 host with port", "http://[2001:db8::1]:8080/feed", "http://[2001:db8::1]:8080/feed"},
+		{"keeps query order", "https://example.com/feed?b=2&a=1", "https://example.com/feed?b=2&a=1"},
+		{"keeps query encoding", "https://example.com/feed?sig=a%2Bb&x", "https://example.com/feed?sig=a%2Bb&x"},
+		{"drops empty query", "https://example.com/feed?", "https://example.com/feed"},
+		{"drops fragment", "https://example.com/feed#top", "https://example.com/feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeFeedURL(tt.in)
+			if err != nil {
+				t.Fatalf("NormalizeFeedURL(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeFeedURL(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFeedURLInvalid(t *testing.T) {
+	for _, in := range []string{"", "   ", "ftp://example.com/feed", "https://", "https:///feed", "http://%zz"} {
+		if got, err := NormalizeFeedURL(in); !errors.Is(err, ErrInvalidFeedURL) {
+			t.Errorf("NormalizeFeedURL(%q) = %q, %v, want ErrInvalidFeedURL", in, got, err)
+		}
+	}
+}
+
+func TestFeedURLKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"http and https", "http://example.com/feed", "https://example.com/feed", true},
+		{"www prefix", "https://www.example.com/feed", "https://example.com/feed", true},
+		{"trailing slash", "https://example.com/feed/", "https://example.com/feed", true},
+		{"root", "https://example.com/", "https://www.example.com", true},
+		{"default port", "https://example.com:443/feed", "http://example.com/feed", true},
+		{"query order", "https://example.com/feed?a=1&b=2", "https://example.com/feed?b=2&a=1", true},
+		{"empty query", "https://example.com/feed?", "https://example.com/feed", true},
+		{"ipv6", "https://[2001:db8::1]:443/feed", "http://[2001:DB8::1]/feed/", true},
+		{"other port", "https://example.com:8443/feed", "https://example.com/feed", false},
+		{"other path", "https://example.com/feed", "https://example.com/rss", false},
+		{"path case", "https://example.com/Feed", "https://example.com/feed", false},
+		{"other subdomain", "https://blog.example.com/feed", "https://example.com/feed", false},
+		{"other query", "https://example.com/feed?a=1", "https://example.com/feed?a=2", false},
+		{"valueless parameter", "https://example.com/feed?a", "https://example.com/feed?a=", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA, keyB := urlKey(t, tt.a), urlKey(t, tt.b)
+			if (keyA == keyB) != tt.same {
+				t.Errorf("FeedURLKey: %q -> %q, %q -> %q, want same=%v", tt.a, keyA, tt.b, keyB, tt.same)
+			}
+		})
+	}
+}
+
+func urlKey(t *testing.T, rawURL string) string {
+	t.Helper()
+
+	feedURL, err := NormalizeFeedURL(rawURL)
+	if err != nil {
+		t.Fatalf("NormalizeFeedURL(%q) error: %v", rawURL, err)
+	}
+
+	return FeedURLKey(feedURL)
+}
@@ -6,67 +6,144 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/metrics"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
 )
 
+// WorkerConfig controls how the Worker schedules fetches
+type WorkerConfig struct {
+	// Interval is the minimum time between two fetches of the same feed
+	Interval time.Duration
+	// PoolSize is the number of feeds fetched concurrently
+	PoolSize int
+	// QueueDepth is the number of claimed feeds waiting for a free fetcher
+	QueueDepth int
+	// IdleDelay is how long the producer waits before polling again when no feed is due
+	IdleDelay time.Duration
+}
+
+// Worker continuously fetches due feeds. A producer claims feeds and keeps the
+// queue topped up while a fixed pool of fetchers drains it, so a slow feed only
+// occupies its own slot instead of stalling a whole batch.
 type Worker struct {
 	feedService *FeedService
-	interval    time.Duration
-	concurrency int
+	cfg         WorkerConfig
+
+	// freed is signalled whenever a fetcher finishes and a queue slot may be available
+	freed chan struct{}
 }
 
-func NewWorker(feedService *FeedService, interval time.Duration, concurrency int) *Worker {
+func NewWorker(feedService *FeedService, cfg WorkerConfig) *Worker {
+	if cfg.PoolSize < 1 {
+		cfg.PoolSize = 1
+	}
+	if cfg.QueueDepth < 1 {
+		cfg.QueueDepth = cfg.PoolSize
+	}
+	if cfg.IdleDelay <= 0 {
+		cfg.IdleDelay = cfg.Interval
+	}
+
 	return &Worker{
 		feedService: feedService,
-		interval:    interval,
-		concurrency: concurrency,
+		cfg:         cfg,
+		freed:       make(chan struct{}, 1),
 	}
 }
 
+// Start runs the scheduler until ctx is cancelled. It returns once every
+// fetcher has finished, so callers can wait on it during shutdown.
 func (w *Worker) Start(ctx context.Context) {
-	log.Printf("Scraping on %v goroutines every %v\n duration", w.concurrency, w.interval)
-	ticker := time.NewTicker(w.interval)
-	defer ticker.Stop()
+	log.Printf("Scraping on %v goroutines, refetching feeds every %v", w.cfg.PoolSize, w.cfg.Interval)
+
+	queue := make(chan *models.Feed, w.cfg.QueueDepth)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.PoolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.fetchLoop(ctx, queue)
+		}()
+	}
+
+	w.produce(ctx, queue)
+
+	close(queue)
+	wg.Wait()
+
+	log.Println("Feed Worker Shutting Down")
+}
+
+// produce claims due feeds whenever the queue has room until ctx is cancelled
+func (w *Worker) produce(ctx context.Context, queue chan<- *models.Feed) {
+	idle := time.NewTimer(w.cfg.IdleDelay)
+	idle.Stop()
 
 	for {
+		full := false
+
+		if free := cap(queue) - len(queue); free > 0 {
+			feeds, err := w.feedService.ClaimFeedsToFetch(ctx, free, w.cfg.Interval)
+			if err != nil && ctx.Err() == nil {
+				log.Println("Error fetching feeds: ", err)
+			}
+
+			// Only the producer sends, so the queue has room for every claimed feed
+			for _, feed := range feeds {
+				queue <- feed
+			}
+			metrics.SetFetchQueueDepth(len(queue))
+			full = len(feeds) == free
+		} else {
+			full = true
+		}
+
+		// A full queue waits for a fetcher to free a slot, otherwise nothing is
+		// due right now and we poll again after the idle delay
+		if full {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.freed:
+			}
+			continue
+		}
+
+		idle.Reset(w.cfg.IdleDelay)
 		select {
 		case <-ctx.Done():
-			log.Println("Feed Worker Shutting Down")
 			return
-
-		case <-ticker.C:
-			w.runOnce(ctx)
+		case <-idle.C:
 		}
 	}
 }
 
-func (w *Worker) runOnce(ctx context.Context) {
-	feeds, err := w.feedService.GetNextFeedsToFetch(ctx, w.concurrency, w.interval)
-	if err != nil {
-		log.Println("Error fetching feeds: ", err)
-		return
-	}
-
-	sem := make(chan struct{}, w.concurrency)
-	var wg sync.WaitGroup
+// fetchLoop fetches queued feeds until the queue is closed
+func (w *Worker) fetchLoop(ctx context.Context, queue <-chan *models.Feed) {
+	for feed := range queue {
+		metrics.SetFetchQueueDepth(len(queue))
 
-	for _, feed := range feeds {
-		wg.Add(1)
-		sem <- struct{}{}
+		// Feeds still queued at shutdown are skipped and handed back, so other
+		// instances can fetch them without waiting for the lease to expire
+		if ctx.Err() == nil {
+			w.fetch(ctx, feed)
+		} else if err := w.feedService.ReleaseFeed(context.WithoutCancel(ctx), feed); err != nil {
+			log.Printf("Failed to release lease on feed %s: %v", feed.FeedURL, err)
+		}
 
-		go func(f *models.Feed) {
-			defer wg.Done()
-			defer func() {
-				<-sem
-			}()
-
-			if err := w.feedService.FetchAndStoreFeed(ctx, f); err != nil {
-				log.Printf("Error processing feed: %v, with link: %s", err, f.FeedURL)
-			} else {
-				log.Printf("Successfully fetched and stored feed: %s", f.FeedURL)
-			}
-		}(feed)
+		select {
+		case w.freed <- struct{}{}:
+		default:
+		}
 	}
+}
 
-	wg.Wait()
+// fetch fetches a single feed, the fetcher bounds the request with its timeout
+func (w *Worker) fetch(ctx context.Context, feed *models.Feed) {
+	if inserted, err := w.feedService.FetchAndStoreFeed(ctx, feed); err != nil {
+		log.Printf("Error processing feed: %v, with link: %s", err, feed.FeedURL)
+	} else {
+		log.Printf("Successfully fetched and stored feed: %s (%d new articles)", feed.FeedURL, inserted)
+	}
 }
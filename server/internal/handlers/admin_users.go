@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// AdminUserHandler contains HTTP handlers for managing users
+type AdminUserHandler struct {
+	authService *auth.AuthService
+}
+
+// NewAdminUserHandler creates a new AdminUser handler
+func NewAdminUserHandler(authService *auth.AuthService) *AdminUserHandler {
+	return &AdminUserHandler{
+		authService: authService,
+	}
+}
+
+// ListUsersHandler godoc
+// @Summary      List users
+// @Description  List users ordered by email. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        offset query int false "Offset" default(0)
+// @Param        limit query int false "Limit (max 200)" default(50)
+// @Security     BearerAuth
+// @Success      200 {object} dto.ListUsersResponse
+// @Failure      400 {string} string "Invalid Query Parameters"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/users [get]
+func (h *AdminUserHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	offset, limit := 0, 50
+	var err error
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "Invalid Query Parameters", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 200 {
+			http.Error(w, "Invalid Query Parameters", http.StatusBadRequest)
+			return
+		}
+	}
+
+	users, total, err := h.authService.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		log.Printf("list users: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newAdminUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ListUsersResponse{
+		Users: response,
+		Total: total,
+	})
+}
+
+// GetUserHandler godoc
+// @Summary      Get a user
+// @Description  Retrieve a single user. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.AdminUserResponse
+// @Failure      400 {string} string "Invalid User ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "User Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/users/{id} [get]
+func (h *AdminUserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.GetUser(r.Context(), userID)
+	if err != nil {
+		writeAdminUserError(w, "get user", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserResponse(user))
+}
+
+// SetUserRoleHandler godoc
+// @Summary      Change a user's role
+// @Description  Make a user an admin or a regular user. Demotions apply immediately, promotions once the user's access token is refreshed. The last admin can't be demoted. Admin only.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Param        request body dto.SetUserRoleRequest true "New role"
+// @Security     BearerAuth
+// @Success      200 {object} dto.AdminUserResponse
+// @Failure      400 {string} string "Invalid Role"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "User Not Found"
+// @Failure      409 {string} string "Cannot remove the last admin"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/users/{id}/role [put]
+func (h *AdminUserHandler) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.SetUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.SetUserRole(r.Context(), userID, req.Role, clientInfo(r))
+	if err != nil {
+		writeAdminUserError(w, "set user role", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserResponse(user))
+}
+
+// DeleteUserHandler godoc
+// @Summary      Delete a user
+// @Description  Permanently delete another user's account with its sessions, subscriptions and article states. Admins delete their own account through the profile. Admin only.
+// @Tags         Admin
+// @Param        id path string true "User ID"
+// @Security     BearerAuth
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid User ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "User Not Found"
+// @Failure      409 {string} string "Cannot remove the last admin"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/users/{id} [delete]
+func (h *AdminUserHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid User ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DeleteUser(r.Context(), adminID, userID); err != nil {
+		writeAdminUserError(w, "delete user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAdminUserError maps errors of user management to responses
+func writeAdminUserError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRole):
+		http.Error(w, "Invalid Role", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrDeleteSelf):
+		http.Error(w, "Delete your own account from your profile", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrUserNotFound):
+		http.Error(w, "User Not Found", http.StatusNotFound)
+	case errors.Is(err, auth.ErrLastAdmin):
+		http.Error(w, "Cannot remove the last admin", http.StatusConflict)
+	default:
+		log.Printf("%s: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func newAdminUserResponse(user *models.User) dto.AdminUserResponse {
+	response := dto.AdminUserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified(),
+		MFAEnabled:    user.TOTPEnabled(),
+		CreatedAt:     user.CreatedAt,
+	}
+
+	if user.LastLogin.Valid {
+		lastLogin := user.LastLogin.Time
+		response.LastLogin = &lastLogin
+	}
+
+	return response
+}
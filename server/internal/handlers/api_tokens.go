@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// APITokenHandler contains HTTP handlers for personal access tokens
+type APITokenHandler struct {
+	authService *auth.AuthService
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(authService *auth.AuthService) *APITokenHandler {
+	return &APITokenHandler{
+		authService: authService,
+	}
+}
+
+// CreateAPITokenHandler godoc
+// @Summary      Create a personal access token
+// @Description  Create a long-lived token for scripts, sent as "Bearer <token>" like an access token. It only works on routes needing one of its scopes: articles:read, articles:write, subscriptions:write or admin. The token is only shown in this response, only a hash is stored.
+// @Tags         API Tokens
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.CreateAPITokenRequest true "Name, scopes and optional expiry"
+// @Success      201 {object} dto.CreateAPITokenResponse
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      400 {string} string "Name must be between 1 and 100 characters"
+// @Failure      400 {string} string "Invalid Scope"
+// @Failure      400 {string} string "Expiry must be in the future"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      409 {string} string "Too Many Tokens"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /tokens [post]
+func (h *APITokenHandler) CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	token, raw, err := h.authService.CreateAPIToken(r.Context(), userID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidAPITokenName):
+			http.Error(w, "Name must be between 1 and 100 characters", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrInvalidScope):
+			http.Error(w, "Invalid Scope", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrInvalidExpiry):
+			http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrTooManyAPITokens):
+			http.Error(w, "Too Many Tokens", http.StatusConflict)
+		default:
+			log.Printf("create api token: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateAPITokenResponse{
+		APITokenResponse: toAPITokenResponse(token),
+		Token:            raw,
+	})
+}
+
+// ListAPITokensHandler godoc
+// @Summary      List personal access tokens
+// @Description  List the authenticated user's personal access tokens that haven't expired, newest first
+// @Tags         API Tokens
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.ListAPITokensResponse
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /tokens [get]
+func (h *APITokenHandler) ListAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("list api tokens: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.APITokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, toAPITokenResponse(token))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ListAPITokensResponse{
+		Tokens: response,
+	})
+}
+
+// RevokeAPITokenHandler godoc
+// @Summary      Revoke a personal access token
+// @Description  Delete one of the authenticated user's personal access tokens, it stops working immediately
+// @Tags         API Tokens
+// @Param        id path string true "Token ID"
+// @Security     BearerAuth
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid Token ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Token Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /tokens/{id} [delete]
+func (h *APITokenHandler) RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(r.Context(), userID, tokenID); err != nil {
+		if errors.Is(err, auth.ErrAPITokenNotFound) {
+			http.Error(w, "Token Not Found", http.StatusNotFound)
+			return
+		}
+
+		log.Printf("revoke api token: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toAPITokenResponse(token *models.APIToken) dto.APITokenResponse {
+	response := dto.APITokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		CreatedAt:  token.CreatedAt,
+		LastUsedIP: token.LastUsedIP.String,
+	}
+	if token.ExpiresAt.Valid {
+		response.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		response.LastUsedAt = &token.LastUsedAt.Time
+	}
+
+	return response
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
 )
 
 // AuthHandler contains HTTP handlers for authentication
@@ -16,14 +19,17 @@ type AuthHandler struct {
 	authService     *auth.AuthService
 	refreshTokenTTL time.Duration
 	cookieSecure    bool
+	// appBaseURL is the frontend single sign-on logins return to
+	appBaseURL string
 }
 
 // NewAuthHandler creates a new Auth handler
-func NewAuthHandler(authService *auth.AuthService, refreshTTL time.Duration, cookieSecure bool) *AuthHandler {
+func NewAuthHandler(authService *auth.AuthService, refreshTTL time.Duration, cookieSecure bool, appBaseURL string) *AuthHandler {
 	return &AuthHandler{
 		authService:     authService,
 		refreshTokenTTL: refreshTTL,
 		cookieSecure:    cookieSecure,
+		appBaseURL:      strings.TrimRight(appBaseURL, "/"),
 	}
 }
 
@@ -41,7 +47,8 @@ func (h *AuthHandler) setRefreshTokenCookie(w http.ResponseWriter, tokenString s
 	})
 }
 
-func (h *AuthHandler) clearRefreshTokenCookie(w http.ResponseWriter) {
+// clearRefreshTokenCookie removes the refresh token cookie from the browser
+func clearRefreshTokenCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     refreshTokenCookieName,
 		Value:    "",
@@ -51,15 +58,29 @@ func (h *AuthHandler) clearRefreshTokenCookie(w http.ResponseWriter) {
 	})
 }
 
+// clientInfo describes the device a request comes from
+func clientInfo(r *http.Request) auth.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return auth.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
+
 // Register godoc
 // @Summary      Register a new user
-// @Description  Create a new user account with email, username, and password
+// @Description  Create a new user account with email, username, and password. A verification link is emailed to the address, until it is followed the account can't add feeds or subscribe.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        request body dto.RegisterRequest true "Registration credentials"
 // @Success      201 {object} dto.RegisterResponse
 // @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      400 {string} string "Invalid Email Address"
 // @Failure      409 {string} string "Email already in use"
 // @Failure      500 {string} string "Error Creating User"
 // @Router       /auth/register [post]
@@ -84,6 +105,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Email already in use", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, auth.ErrInvalidEmail) {
+			http.Error(w, "Invalid Email Address", http.StatusBadRequest)
+			return
+		}
 
 		// Log the underlying error for debugging
 		log.Printf("register: failed to create user: %v", err)
@@ -96,6 +121,8 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		ID:       user.ID.String(),
 		Email:    user.Email,
 		Username: user.Username,
+
+		EmailVerified: user.EmailVerified(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -105,7 +132,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary      Login user
-// @Description  Authenticate user and receive JWT access token
+// @Description  Authenticate user and receive JWT access token. Every login starts a new session, sessions on other devices stay signed in. Accounts with two-factor authentication get mfa_required and an MFA challenge to complete at /auth/login/mfa instead.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -125,7 +152,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Attempt to login
-	accessToken, refreshToken, err := h.authService.LoginWithRefresh(req.Email, req.Password, h.refreshTokenTTL)
+	client := clientInfo(r)
+	client.DeviceName = req.DeviceName
+
+	result, err := h.authService.LoginWithRefresh(req.Email, req.Password, h.refreshTokenTTL, client)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			http.Error(w, "Invalid Credentials", http.StatusUnauthorized)
@@ -136,18 +166,77 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The password checked out, but the second factor is still missing
+	if result.MFARequired() {
+		response := dto.LoginResponse{
+			MFARequired:  true,
+			MFAToken:     result.MFAToken,
+			MFAExpiresAt: &result.MFAExpiresAt,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	// Set the refresh token as an HttpOnly cookie
-	h.setRefreshTokenCookie(w, refreshToken)
+	h.setRefreshTokenCookie(w, result.RefreshToken)
 
 	// Return the token
 	response := dto.LoginResponse{
-		AccessToken: accessToken,
+		AccessToken: result.AccessToken,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
+// LoginMFA godoc
+// @Summary      Complete login with a second factor
+// @Description  Exchange the MFA challenge returned by /auth/login and a TOTP code or unused recovery code for an access token. Five attempts are allowed per five minutes.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MFALoginRequest true "MFA challenge and code"
+// @Success      200 {object} dto.LoginResponse "Access token; refresh token is set as an HttpOnly cookie"
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      401 {string} string "Invalid or Expired MFA Challenge"
+// @Failure      401 {string} string "Invalid Code"
+// @Failure      429 {string} string "Too Many Attempts"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	accessToken, refreshToken, err := h.authService.CompleteMFALogin(r.Context(), req.MFAToken, req.Code, h.refreshTokenTTL, clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrExpiredToken), errors.Is(err, auth.ErrMFANotEnrolled):
+			http.Error(w, "Invalid or Expired MFA Challenge", http.StatusUnauthorized)
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			http.Error(w, "Invalid Code", http.StatusUnauthorized)
+		case errors.Is(err, auth.ErrTooManyRequests):
+			http.Error(w, "Too Many Attempts", http.StatusTooManyRequests)
+		default:
+			log.Printf("mfa login: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.setRefreshTokenCookie(w, refreshToken)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.LoginResponse{
+		AccessToken: accessToken,
+	})
+}
+
 // RefreshToken godoc
 // @Summary      Refresh access token
 // @Description  Get a new access token using a valid refresh token
@@ -182,7 +271,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Attempt to refresh the token
-	accessToken, newRefreshToken, err := h.authService.RefreshAccessToken(tokenStr, h.refreshTokenTTL)
+	accessToken, newRefreshToken, err := h.authService.RefreshAccessToken(tokenStr, h.refreshTokenTTL, clientInfo(r))
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
 			http.Error(w, "Invalid or Expired refresh token", http.StatusUnauthorized)
@@ -207,7 +296,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 // Logout godoc
 // @Summary      Logout user
-// @Description  Revoke refresh token. The refresh token is read from the HttpOnly cookie named "refresh_token" by default; clients may optionally pass it in the request body.
+// @Description  Sign out the session the refresh token belongs to. The refresh token is read from the HttpOnly cookie named "refresh_token" by default; clients may optionally pass it in the request body.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -238,6 +327,148 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear cookie on logout
-	h.clearRefreshTokenCookie(w)
+	clearRefreshTokenCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Confirm the email address with the token from the verification link. A pending email from a change of address replaces the current one now. Tokens work once and expire. Access tokens issued before keep working, the account is unlocked right away.
+// @Tags         Authentication
+// @Accept       json
+// @Param        request body dto.VerifyEmailRequest true "Verification token"
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid or Expired Verification Token"
+// @Failure      409 {string} string "Email already in use"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			http.Error(w, "Invalid or Expired Verification Token", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, auth.ErrEmailInUse) {
+			http.Error(w, "Email already in use", http.StatusConflict)
+			return
+		}
+
+		log.Printf("verify email: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Email the authenticated user a new verification link, to the pending email if they are changing address. Earlier links stop working. Can be requested once a minute.
+// @Tags         Authentication
+// @Security     BearerAuth
+// @Success      202 "Accepted"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      409 {string} string "Email Already Verified"
+// @Failure      429 {string} string "Verification Email Sent Recently"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.ResendVerificationEmail(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrEmailAlreadyVerified):
+			http.Error(w, "Email Already Verified", http.StatusConflict)
+		case errors.Is(err, auth.ErrVerificationThrottled):
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Verification Email Sent Recently", http.StatusTooManyRequests)
+		default:
+			log.Printf("resend verification: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link to the account with this address. The response is the same whether or not the address has an account.
+// @Tags         Authentication
+// @Accept       json
+// @Param        request body dto.ForgotPasswordRequest true "Account email"
+// @Success      202 "Accepted"
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      429 {string} string "Too Many Requests"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ForgotPassword(r.Context(), req.Email, clientInfo(r)); err != nil {
+		if errors.Is(err, auth.ErrTooManyRequests) {
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		log.Printf("forgot password: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from a password reset email. Every session of the account is signed out.
+// @Tags         Authentication
+// @Accept       json
+// @Param        request body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid or Expired Reset Token"
+// @Failure      400 {string} string "Password must be between 8 and 72 bytes long"
+// @Failure      429 {string} string "Too Many Requests"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.Password, clientInfo(r)); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			http.Error(w, "Invalid or Expired Reset Token", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrInvalidPassword):
+			http.Error(w, "Password must be between 8 and 72 bytes long", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrTooManyRequests):
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		default:
+			log.Printf("reset password: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The session this browser had is gone too
+	clearRefreshTokenCookie(w)
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminUserResponse represents a user as seen by admins
+type AdminUserResponse struct {
+	ID            uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Email         string     `json:"email" example:"user@example.com"`
+	Username      string     `json:"username" example:"johndoe"`
+	Role          string     `json:"role" example:"user" enums:"user,admin"`
+	EmailVerified bool       `json:"email_verified" example:"true"`
+	MFAEnabled    bool       `json:"mfa_enabled" example:"false"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastLogin     *time.Time `json:"last_login,omitempty"`
+}
+
+// ListUsersResponse represents a page of users
+type ListUsersResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	// Total is the number of users across every page
+	Total int `json:"total" example:"42"`
+}
+
+// SetUserRoleRequest changes the role of a user
+type SetUserRoleRequest struct {
+	Role string `json:"role" example:"admin" enums:"user,admin"`
+}
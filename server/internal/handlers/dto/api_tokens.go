@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateAPITokenRequest creates a personal access token
+type CreateAPITokenRequest struct {
+	Name string `json:"name" example:"backup script"`
+	// Scopes are any of articles:read, subscriptions:write and admin
+	Scopes []string `json:"scopes" example:"articles:read,subscriptions:write"`
+	// ExpiresAt is optional, tokens without it never expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APITokenResponse describes a personal access token, without the token itself
+type APITokenResponse struct {
+	ID     uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name   string    `json:"name" example:"backup script"`
+	Prefix string    `json:"prefix" example:"sws_pat_k7Mq2x"`
+	Scopes []string  `json:"scopes" example:"articles:read,subscriptions:write"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" example:"203.0.113.7"`
+}
+
+// CreateAPITokenResponse is a new personal access token, Token is only ever shown here
+type CreateAPITokenResponse struct {
+	APITokenResponse
+	Token string `json:"token" example:"sws_pat_k7Mq2xRd9p4aWz3nB2hcXq7eM4tr8ksdLf0vYg1uJ6o"`
+}
+
+// ListAPITokensResponse represents the response for listing personal access tokens
+type ListAPITokensResponse struct {
+	Tokens []APITokenResponse `json:"tokens"`
+}
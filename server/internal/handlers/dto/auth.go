@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 // RegisterRequest represents the registration payload
 type RegisterRequest struct {
 	Email    string `json:"email" example:"user@example.com"`
@@ -12,17 +14,48 @@ type RegisterResponse struct {
 	ID       string `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	Email    string `json:"email" example:"user@example.com"`
 	Username string `json:"username" example:"johndoe"`
+	// EmailVerified is false until the link emailed on registration is followed
+	EmailVerified bool `json:"email_verified" example:"false"`
+}
+
+// VerifyEmailRequest carries the token from an email verification link
+type VerifyEmailRequest struct {
+	Token string `json:"token" example:"q3Jd0F1c2...x9Qk.Yk1pRk...Zw"`
+}
+
+// ForgotPasswordRequest asks for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+// ResetPasswordRequest sets a new password with the token from a reset email
+type ResetPasswordRequest struct {
+	Token    string `json:"token" example:"q3Jd0F1c2...x9Qk.Yk1pRk...Zw"`
+	Password string `json:"password" example:"NewSecurePass123!"`
 }
 
 // LoginRequest represents the login payload
 type LoginRequest struct {
 	Email    string `json:"email" example:"user@example.com"`
 	Password string `json:"password" example:"SecurePass123!"`
+	// DeviceName labels the session in the session list
+	DeviceName string `json:"device_name,omitempty" example:"Pixel 8"`
 }
 
-// LoginResponse contains the JWT token after successful login
+// LoginResponse contains the JWT token after successful login. Accounts with
+// two-factor authentication get an MFA challenge instead, to answer at /auth/login/mfa.
 type LoginResponse struct {
-	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	AccessToken string `json:"access_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+
+	MFARequired  bool       `json:"mfa_required" example:"false"`
+	MFAToken     string     `json:"mfa_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	MFAExpiresAt *time.Time `json:"mfa_expires_at,omitempty"`
+}
+
+// MFALoginRequest completes a login with the MFA challenge and a TOTP or recovery code
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code     string `json:"code" example:"123456"`
 }
 
 // RefreshRequest represents the refresh token payload
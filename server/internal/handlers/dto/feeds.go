@@ -51,10 +51,25 @@ type ListFeedsResponse struct {
 	Feeds []FeedResponse `json:"feeds"`
 }
 
+// SubscriptionResponse represents one of the user's subscriptions with its feed
+type SubscriptionResponse struct {
+	ID          uuid.UUID    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	CustomTitle string       `json:"custom_title" example:"My RSS Feed"`
+	Folder      string       `json:"folder" example:"Tech/Go"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Feed        FeedResponse `json:"feed"`
+}
+
+// ListSubscriptionsResponse represents the response for listing the user's subscriptions
+type ListSubscriptionsResponse struct {
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+}
+
 // SubscribeFeedRequest represents the payload to subscribe to a feed
 type SubscribeFeedRequest struct {
 	FeedID      uuid.UUID `json:"feed_id" example:"17b3a6f1-1617-4104-b914-fffba0236bd9"`
 	CustomTitle string    `json:"custom_title" example:"My RSS Feed"`
+	Folder      string    `json:"folder,omitempty" example:"Tech/Go"`
 }
 
 // SubscribeFeedResponse represents the response after subscribing to a feed
@@ -66,3 +81,53 @@ type SubscribeFeedResponse struct {
 type GetUserArticlesResponse struct {
 	Articles []ArticlesResponse `json:"articles"`
 }
+
+// SubscribeByURLRequest represents the payload to subscribe to a feed by its URL
+type SubscribeByURLRequest struct {
+	URL         string `json:"url" example:"https://go.dev/blog/feed.atom"`
+	CustomTitle string `json:"custom_title,omitempty" example:"The Go Blog"`
+	Folder      string `json:"folder,omitempty" example:"Tech/Go"`
+}
+
+// SubscribeByURLResponse represents the response after subscribing to a feed by its URL
+type SubscribeByURLResponse struct {
+	FeedID      uuid.UUID `json:"feed_id" example:"17b3a6f1-1617-4104-b914-fffba0236bd9"`
+	FeedURL     string    `json:"feed_url" example:"https://go.dev/blog/feed.atom"`
+	Title       string    `json:"title" example:"The Go Blog"`
+	FeedCreated bool      `json:"feed_created" example:"true"`
+	Subscribed  bool      `json:"subscribed" example:"true"`
+	Message     string    `json:"message" example:"Successfully subscribed to the feed"`
+}
+
+// FeedRetentionRequest represents the payload to override a feed's retention policy.
+// Omitted or null fields fall back to the global default, 0 keeps articles forever.
+type FeedRetentionRequest struct {
+	RetentionDays *int `json:"retention_days" example:"30"`
+	MaxArticles   *int `json:"max_articles" example:"500"`
+}
+
+// FeedRetentionResponse represents a feed's retention overrides
+type FeedRetentionResponse struct {
+	FeedID        uuid.UUID `json:"feed_id" example:"17b3a6f1-1617-4104-b914-fffba0236bd9"`
+	RetentionDays *int      `json:"retention_days" example:"30"`
+	MaxArticles   *int      `json:"max_articles" example:"500"`
+}
+
+// ArticleStateRequest represents the payload to change a user's state of an
+// article. Omitted or null fields keep their current value.
+type ArticleStateRequest struct {
+	Read    *bool   `json:"read" example:"true"`
+	Starred *bool   `json:"starred" example:"true"`
+	Saved   *bool   `json:"saved" example:"false"`
+	Note    *string `json:"note" example:"Worth a second read"`
+}
+
+// ArticleStateResponse represents a user's state of an article
+type ArticleStateResponse struct {
+	ArticleID uuid.UUID `json:"article_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Read      bool      `json:"read" example:"true"`
+	Starred   bool      `json:"starred" example:"true"`
+	Saved     bool      `json:"saved" example:"false"`
+	Note      string    `json:"note" example:"Worth a second read"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobResponse represents a background job in responses
+type JobResponse struct {
+	ID      uuid.UUID       `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Kind    string          `json:"kind" example:"jobs.cleanup"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+	// PayloadRedacted is set when the payload may hold secrets, payload is then null
+	PayloadRedacted bool            `json:"payload_redacted,omitempty" example:"false"`
+	Status          string          `json:"status" example:"pending" enums:"pending,running,completed,dead"`
+	Attempts        int             `json:"attempts" example:"1"`
+	MaxAttempts     int             `json:"max_attempts" example:"5"`
+	RunAt           time.Time       `json:"run_at"`
+	UniqueKey       string          `json:"unique_key,omitempty" example:"recurring:jobs.cleanup"`
+	LastError       string          `json:"last_error,omitempty"`
+	LockedBy        string          `json:"locked_by,omitempty"`
+	LockedUntil     *time.Time      `json:"locked_until,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	UserID          *uuid.UUID      `json:"user_id,omitempty"`
+	Result          json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+}
+
+// ListJobsResponse represents the response for listing jobs
+type ListJobsResponse struct {
+	Jobs []JobResponse `json:"jobs"`
+}
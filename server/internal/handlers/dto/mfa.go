@@ -0,0 +1,37 @@
+package dto
+
+// MFAStatusResponse describes the two-factor authentication of the user
+type MFAStatusResponse struct {
+	TOTPEnabled       bool `json:"totp_enabled" example:"true"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left" example:"8"`
+}
+
+// EnrollTOTPRequest starts TOTP enrollment, the password is required
+type EnrollTOTPRequest struct {
+	Password string `json:"password" example:"SecurePass123!"`
+}
+
+// EnrollTOTPResponse is the new secret to add to an authenticator app
+type EnrollTOTPResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Swayamsevak:user@example.com?algorithm=SHA1&digits=6&issuer=Swayamsevak&period=30&secret=JBSWY3DPEHPK3PXP"`
+	// QRCodePNG is a base64 encoded PNG of the otpauth URI
+	QRCodePNG []byte `json:"qr_code_png" swaggertype:"string" format:"base64"`
+}
+
+// MFACodeRequest carries a TOTP code, or a recovery code where accepted
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// RecoveryCodesResponse lists recovery codes, they are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7mq-2xrd-9p4a-wz3n,b2hc-xq7e-m4tr-8ksd"`
+}
+
+// DisableTOTPRequest turns off two-factor authentication
+type DisableTOTPRequest struct {
+	Password string `json:"password" example:"SecurePass123!"`
+	// Code is a TOTP code or a recovery code
+	Code string `json:"code" example:"123456"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OPMLImportResult represents the outcome of importing a single outline
+type OPMLImportResult struct {
+	Text    string `json:"text" example:"Go Blog"`
+	FeedURL string `json:"feed_url" example:"https://go.dev/blog/feed.atom"`
+	Folder  string `json:"folder,omitempty" example:"Tech/Go"`
+	Status  string `json:"status" example:"imported" enums:"imported,already_subscribed,invalid,failed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OPMLImportResponse represents the state of an OPML import
+type OPMLImportResponse struct {
+	ID         uuid.UUID          `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Status     string             `json:"status" example:"completed" enums:"pending,running,completed,failed"`
+	Total      int                `json:"total" example:"12"`
+	Processed  int                `json:"processed" example:"12"`
+	Results    []OPMLImportResult `json:"results"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeedRefreshResult represents the outcome of refreshing a single feed
+type FeedRefreshResult struct {
+	FeedID      uuid.UUID `json:"feed_id" example:"17b3a6f1-1617-4104-b914-fffba0236bd9"`
+	FeedURL     string    `json:"feed_url" example:"https://go.dev/blog/feed.atom"`
+	NewArticles int64     `json:"new_articles" example:"3"`
+	Error       string    `json:"error,omitempty" example:"feed could not be reached"`
+}
+
+// RefreshJobResponse represents the state of an on-demand refresh
+type RefreshJobResponse struct {
+	ID          uuid.UUID           `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Status      string              `json:"status" example:"completed" enums:"pending,running,completed,failed"`
+	Total       int                 `json:"total" example:"1"`
+	NewArticles int64               `json:"new_articles" example:"3"`
+	Results     []FeedRefreshResult `json:"results"`
+	CreatedAt   time.Time           `json:"created_at"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionResponse represents a signed-in device
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	DeviceName string    `json:"device_name" example:"Pixel 8"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Linux; Android 14)"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session the request was made from
+	Current bool `json:"current" example:"true"`
+}
+
+// ListSessionsResponse represents the response for listing sessions
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// RevokeOtherSessionsResponse represents the response after signing out everywhere else
+type RevokeOtherSessionsResponse struct {
+	Revoked int64 `json:"revoked" example:"2"`
+}
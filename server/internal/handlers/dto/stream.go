@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamTicketResponse represents a short-lived ticket to open the article stream
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UnreadCountEvent is sent on the article stream when a feed's unread count changes
+type UnreadCountEvent struct {
+	FeedID uuid.UUID `json:"feed_id" example:"17b3a6f1-1617-4104-b914-fffba0236bd9"`
+	Unread int64     `json:"unread" example:"12"`
+}
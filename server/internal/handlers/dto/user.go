@@ -5,4 +5,40 @@ type UserResponse struct {
 	ID       string `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	Email    string `json:"email" example:"user@example.com"`
 	Username string `json:"username" example:"johndoe"`
+	// EmailVerified tells whether the user confirmed their email address
+	EmailVerified bool `json:"email_verified" example:"true"`
+	// PendingEmail is the address the user is moving to, until they verify it
+	PendingEmail string `json:"pending_email,omitempty" example:"new@example.com"`
+	// HasPassword is false for accounts created through single sign-on until
+	// they set a password with a password reset
+	HasPassword bool `json:"has_password" example:"true"`
+	// Role is "user" or "admin"
+	Role string `json:"role" example:"user"`
+}
+
+// UpdateProfileRequest changes the authenticated user's profile
+type UpdateProfileRequest struct {
+	Username string `json:"username" example:"janedoe"`
+}
+
+// ChangePasswordRequest replaces the password, the current one is required
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"SecurePass123!"`
+	NewPassword     string `json:"new_password" example:"EvenMoreSecure456!"`
+}
+
+// ChangePasswordResponse reports how many other sessions were signed out
+type ChangePasswordResponse struct {
+	RevokedSessions int64 `json:"revoked_sessions" example:"2"`
+}
+
+// ChangeEmailRequest moves the account to a new email address, the password is required
+type ChangeEmailRequest struct {
+	Email    string `json:"email" example:"jane@example.com"`
+	Password string `json:"password" example:"SecurePass123!"`
+}
+
+// DeleteAccountRequest confirms deleting the account with the password
+type DeleteAccountRequest struct {
+	Password string `json:"password" example:"SecurePass123!"`
 }
@@ -3,12 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
 	"github.com/google/uuid"
 )
 
@@ -26,14 +28,16 @@ func NewFeedHandler(feedService *feeds.FeedService) *FeedHandler {
 
 // AddFeedHandler godoc
 // @Summary      Add a new RSS feed
-// @Description  Register a new RSS/Atom feed URL in the system for aggregation
-// @Tags         Feeds
+// @Description  Register a new RSS/Atom feed URL in the system for aggregation. Admin only, users subscribe by URL instead.
+// @Tags         Admin
 // @Accept       json
 // @Produce      json
 // @Param        request body dto.AddFeedRequest true "Feed registration details"
 // @Security     BearerAuth
 // @Success      201 {object} dto.AddFeedResponse "Feed successfully registered"
-// @Failure      400 {string} string "Invalid Request Body"
+// @Failure      400 {string} string "Invalid Request Body or Feed URL"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
 // @Failure      409 {string} string "Feed already exists, aborting"
 // @Failure      500 {string} string "Internal Server Error"
 // @Router       /feed [post]
@@ -60,6 +64,10 @@ func (h *FeedHandler) AddFeedHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Feed already exists, aborting", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, feeds.ErrInvalidFeedURL) {
+			http.Error(w, "Invalid Feed URL", http.StatusBadRequest)
+			return
+		}
 
 		http.Error(w, "Error Creating Feed", http.StatusInternalServerError)
 		return
@@ -77,11 +85,13 @@ func (h *FeedHandler) AddFeedHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetAllFeedsHandler godoc
 // @Summary      Get all RSS feeds
-// @Description  Retrieve all registered RSS/Atom feeds in the system
-// @Tags         Feeds
+// @Description  Retrieve all registered RSS/Atom feeds in the system. Admin only, users list their subscriptions instead.
+// @Tags         Admin
 // @Produce      json
 // @Security     BearerAuth
 // @Success      200 {object} dto.ListFeedsResponse "List of all registered feeds"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
 // @Failure      500 {string} string "Internal Server Error"
 // @Router       /feeds [get]
 func (h *FeedHandler) GetAllFeedsHandler(w http.ResponseWriter, r *http.Request) {
@@ -93,22 +103,7 @@ func (h *FeedHandler) GetAllFeedsHandler(w http.ResponseWriter, r *http.Request)
 
 	response := make([]dto.FeedResponse, 0, len(feeds))
 	for _, feed := range feeds {
-		var lastFetched *time.Time
-		if feed.LastFetchedAt.Valid {
-			t := feed.LastFetchedAt.Time
-			lastFetched = &t
-		}
-
-		response = append(response, dto.FeedResponse{
-			ID:            feed.ID,
-			FeedURL:       feed.FeedURL,
-			SiteURL:       feed.SiteURL,
-			Title:         feed.Title,
-			Description:   feed.Description,
-			CreatedAt:     feed.CreatedAt,
-			UpdatedAt:     feed.UpdatedAt,
-			LastFetchedAt: lastFetched,
-		})
+		response = append(response, toFeedResponse(feed))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -119,6 +114,102 @@ func (h *FeedHandler) GetAllFeedsHandler(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// DeleteFeedHandler godoc
+// @Summary      Delete a feed
+// @Description  Delete a feed for every user, together with its subscriptions and articles. Admin only.
+// @Tags         Admin
+// @Param        id path string true "Feed ID"
+// @Security     BearerAuth
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid Feed ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "Feed Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/feeds/{id} [delete]
+func (h *FeedHandler) DeleteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Feed ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.feedService.DeleteFeed(r.Context(), feedID); err != nil {
+		if errors.Is(err, feeds.ErrFeedNotFound) {
+			http.Error(w, "Feed Not Found", http.StatusNotFound)
+			return
+		}
+
+		log.Printf("delete feed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSubscriptionsHandler godoc
+// @Summary      List subscriptions
+// @Description  List the feeds the authenticated user is subscribed to, ordered by folder and title
+// @Tags         Feeds
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.ListSubscriptionsResponse
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /subscriptions [get]
+func (h *FeedHandler) ListSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subscribedFeeds, err := h.feedService.GetUserSubscriptions(userID)
+	if err != nil {
+		log.Printf("list subscriptions: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.SubscriptionResponse, 0, len(subscribedFeeds))
+	for _, sf := range subscribedFeeds {
+		response = append(response, dto.SubscriptionResponse{
+			ID:          sf.Subscription.ID,
+			CustomTitle: sf.Subscription.CustomTitle,
+			Folder:      sf.Subscription.Folder,
+			CreatedAt:   sf.Subscription.CreatedAt,
+			Feed:        toFeedResponse(&sf.Feed),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ListSubscriptionsResponse{
+		Subscriptions: response,
+	})
+}
+
+// toFeedResponse converts a feed to its API representation
+func toFeedResponse(feed *models.Feed) dto.FeedResponse {
+	var lastFetched *time.Time
+	if feed.LastFetchedAt.Valid {
+		t := feed.LastFetchedAt.Time
+		lastFetched = &t
+	}
+
+	return dto.FeedResponse{
+		ID:            feed.ID,
+		FeedURL:       feed.FeedURL,
+		SiteURL:       feed.SiteURL,
+		Title:         feed.Title,
+		Description:   feed.Description,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		LastFetchedAt: lastFetched,
+	}
+}
+
 // SubscribeToFeedHandler godoc
 // @Summary      Subscribe to an RSS feed
 // @Description  Subscribe the authenticated user to a specific RSS/Atom feed
@@ -129,6 +220,7 @@ func (h *FeedHandler) GetAllFeedsHandler(w http.ResponseWriter, r *http.Request)
 // @Security     BearerAuth
 // @Success      200 {object} dto.SubscribeFeedResponse "Successfully subscribed to the feed"
 // @Failure      400 {string} string "Invalid Request Body"
+// @Failure      403 {string} string "Email Not Verified"
 // @Failure      500 {string} string "Internal Server Error"
 // @Router       /feed/subscribe [post]
 func (h *FeedHandler) SubscribeToFeedHandler(w http.ResponseWriter, r *http.Request) {
@@ -155,7 +247,7 @@ func (h *FeedHandler) SubscribeToFeedHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Call the FeedService to subscribe to the feed
-	err := h.feedService.SubscribeToFeed(userID, req.FeedID, req.CustomTitle)
+	err := h.feedService.SubscribeToFeed(userID, req.FeedID, req.CustomTitle, req.Folder)
 	if err != nil {
 		http.Error(w, "Error Subscribing to Feed", http.StatusInternalServerError)
 		return
@@ -171,6 +263,79 @@ func (h *FeedHandler) SubscribeToFeedHandler(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(response)
 }
 
+// SubscribeByURLHandler godoc
+// @Summary      Subscribe to a feed by URL
+// @Description  Subscribe the authenticated user to the feed at the given URL. The feed is created if nobody added it yet, otherwise the existing feed is reused. Feeds that were never fetched are fetched immediately so articles are available right away.
+// @Tags         Feeds
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.SubscribeByURLRequest true "Feed URL and subscription details"
+// @Security     BearerAuth
+// @Success      201 {object} dto.SubscribeByURLResponse "Successfully subscribed to the feed"
+// @Success      200 {object} dto.SubscribeByURLResponse "Already subscribed to the feed"
+// @Failure      400 {string} string "Invalid Feed URL"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      422 {string} string "URL does not serve a valid feed"
+// @Failure      403 {string} string "Email Not Verified"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /subscriptions [post]
+func (h *FeedHandler) SubscribeByURLHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// Get the user ID from the context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse the request body
+	var req dto.SubscribeByURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate Input
+	if req.URL == "" {
+		http.Error(w, "URL is required", http.StatusBadRequest)
+		return
+	}
+
+	feed, feedCreated, subscribed, err := h.feedService.SubscribeByURL(r.Context(), userID, req.URL, req.CustomTitle, req.Folder)
+	if err != nil {
+		switch {
+		case errors.Is(err, feeds.ErrInvalidFeedURL):
+			http.Error(w, "Invalid Feed URL", http.StatusBadRequest)
+		case errors.Is(err, feeds.ErrFeedUnavailable):
+			http.Error(w, "URL does not serve a valid feed", http.StatusUnprocessableEntity)
+		default:
+			log.Printf("subscribe by url: %v", err)
+			http.Error(w, "Error Subscribing to Feed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := &dto.SubscribeByURLResponse{
+		FeedID:      feed.ID,
+		FeedURL:     feed.FeedURL,
+		Title:       feed.Title,
+		FeedCreated: feedCreated,
+		Subscribed:  subscribed,
+		Message:     "Successfully subscribed to the feed",
+	}
+
+	status := http.StatusCreated
+	if !subscribed {
+		status = http.StatusOK
+		response.Message = "Already subscribed to the feed"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
 // FetchUserArticlesHandler godoc
 // @Summary      Fetch articles for user subscribed feeds
 // @Description  Fetch the articles for feeds subscribed to by a user
@@ -222,3 +387,175 @@ func (h *FeedHandler) GetUserArticlesHandler(w http.ResponseWriter, r *http.Requ
 		Articles: response,
 	})
 }
+
+// SetArticleStateHandler godoc
+// @Summary      Change the state of an article
+// @Description  Mark an article of one of the user's subscriptions read, starred or saved, or annotate it. Omitted fields keep their value. Starred, saved and annotated articles are never deleted by the retention policy.
+// @Tags         Feeds
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Article ID"
+// @Param        request body dto.ArticleStateRequest true "State fields to change"
+// @Security     BearerAuth
+// @Success      200 {object} dto.ArticleStateResponse
+// @Failure      400 {string} string "Invalid Request Body"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Article Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /articles/{id}/state [patch]
+func (h *FeedHandler) SetArticleStateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	articleID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Article ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ArticleStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.feedService.SetArticleState(r.Context(), userID, articleID, feeds.ArticleStateChange{
+		Read:    req.Read,
+		Starred: req.Starred,
+		Saved:   req.Saved,
+		Note:    req.Note,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, feeds.ErrNoteTooLong):
+			http.Error(w, "Note is too long", http.StatusBadRequest)
+		case errors.Is(err, feeds.ErrArticleNotFound):
+			http.Error(w, "Article Not Found", http.StatusNotFound)
+		default:
+			log.Printf("set article state: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ArticleStateResponse{
+		ArticleID: state.ArticleID,
+		Read:      state.Read,
+		Starred:   state.Starred,
+		Saved:     state.Saved,
+		Note:      state.Note,
+		UpdatedAt: state.UpdatedAt,
+	})
+}
+
+// GetFeedRetentionHandler godoc
+// @Summary      Get a feed's retention overrides
+// @Description  Retrieve the article retention overrides of a feed, null values use the global default. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "Feed ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.FeedRetentionResponse
+// @Failure      400 {string} string "Invalid Feed ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "Feed Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/feeds/{id}/retention [get]
+func (h *FeedHandler) GetFeedRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Feed ID", http.StatusBadRequest)
+		return
+	}
+
+	retention, err := h.feedService.GetFeedRetention(r.Context(), feedID)
+	if err != nil {
+		if errors.Is(err, feeds.ErrFeedNotFound) {
+			http.Error(w, "Feed Not Found", http.StatusNotFound)
+			return
+		}
+
+		log.Printf("get feed retention: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newFeedRetentionResponse(retention))
+}
+
+// SetFeedRetentionHandler godoc
+// @Summary      Override a feed's retention policy
+// @Description  Set how long and how many articles of a feed are kept. Null values fall back to the global default, 0 keeps articles forever. Admin only.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Feed ID"
+// @Param        request body dto.FeedRetentionRequest true "Retention overrides"
+// @Security     BearerAuth
+// @Success      200 {object} dto.FeedRetentionResponse
+// @Failure      400 {string} string "Invalid Request Body"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "Feed Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/feeds/{id}/retention [put]
+func (h *FeedHandler) SetFeedRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	feedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Feed ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.FeedRetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Body", http.StatusBadRequest)
+		return
+	}
+
+	retention, err := h.feedService.SetFeedRetention(r.Context(), feedID, req.RetentionDays, req.MaxArticles)
+	if err != nil {
+		switch {
+		case errors.Is(err, feeds.ErrInvalidRetention):
+			http.Error(w, "Retention values must not be negative", http.StatusBadRequest)
+		case errors.Is(err, feeds.ErrFeedNotFound):
+			http.Error(w, "Feed Not Found", http.StatusNotFound)
+		default:
+			log.Printf("set feed retention: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newFeedRetentionResponse(retention))
+}
+
+func newFeedRetentionResponse(retention *models.FeedRetention) dto.FeedRetentionResponse {
+	response := dto.FeedRetentionResponse{
+		FeedID: retention.FeedID,
+	}
+
+	if retention.Days.Valid {
+		days := int(retention.Days.Int32)
+		response.RetentionDays = &days
+	}
+	if retention.MaxArticles.Valid {
+		maxArticles := int(retention.MaxArticles.Int32)
+		response.MaxArticles = &maxArticles
+	}
+
+	return response
+}
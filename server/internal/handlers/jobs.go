@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/mail"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// JobHandler contains HTTP handlers for inspecting background jobs
+type JobHandler struct {
+	jobService *jobs.JobService
+}
+
+// NewJobHandler creates a new Job handler
+func NewJobHandler(jobService *jobs.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// ListJobsHandler godoc
+// @Summary      List background jobs
+// @Description  List background jobs newest first, optionally filtered by status and kind. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        status query string false "Job status" Enums(pending, running, completed, dead)
+// @Param        kind query string false "Job kind"
+// @Param        offset query int false "Offset" default(0)
+// @Param        limit query int false "Limit (max 200)" default(50)
+// @Security     BearerAuth
+// @Success      200 {object} dto.ListJobsResponse
+// @Failure      400 {string} string "Invalid Query Parameters"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /admin/jobs [get]
+func (h *JobHandler) ListJobsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	offset, limit := 0, 50
+	var err error
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "Invalid Query Parameters", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 200 {
+			http.Error(w, "Invalid Query Parameters", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := h.jobService.ListJobs(r.Context(), query.Get("status"), query.Get("kind"), offset, limit)
+	if err != nil {
+		log.Printf("list jobs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.JobResponse, 0, len(list))
+	for _, job := range list {
+		response = append(response, newJobResponse(job))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ListJobsResponse{
+		Jobs: response,
+	})
+}
+
+// GetJobHandler godoc
+// @Summary      Get a background job
+// @Description  Retrieve a single background job including its last error. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "Job ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.JobResponse
+// @Failure      400 {string} string "Invalid Job ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "Job Not Found"
+// @Router       /admin/jobs/{id} [get]
+func (h *JobHandler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobService.GetJob(r.Context(), jobID)
+	if err != nil {
+		h.writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+// RetryJobHandler godoc
+// @Summary      Retry a background job
+// @Description  Re-queue a dead or pending job to run immediately with a fresh set of attempts. A dead job whose unique key, e.g. of a recurring job, is held by a newer queued run cannot be retried. Admin only.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "Job ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.JobResponse
+// @Failure      400 {string} string "Invalid Job ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Forbidden"
+// @Failure      404 {string} string "Job Not Found"
+// @Failure      409 {string} string "Job cannot be retried or another run is already queued"
+// @Router       /admin/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobService.RetryJob(r.Context(), jobID)
+	if err != nil {
+		h.writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+func (h *JobHandler) writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		http.Error(w, "Job Not Found", http.StatusNotFound)
+	case errors.Is(err, jobs.ErrJobNotRetryable):
+		http.Error(w, "Job cannot be retried", http.StatusConflict)
+	case errors.Is(err, jobs.ErrJobAlreadyQueued):
+		http.Error(w, "Another run of this job is already queued", http.StatusConflict)
+	default:
+		log.Printf("jobs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// Payloads of these kinds may hold secrets such as emailed links, or reveal who
+// asked for a password reset, and are never returned
+var redactedJobKinds = map[string]bool{
+	mail.KindSendMail:          true,
+	auth.KindSendPasswordReset: true,
+}
+
+func newJobResponse(job *models.Job) dto.JobResponse {
+	response := dto.JobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		UniqueKey:   job.UniqueKey.String,
+		LastError:   job.LastError.String,
+		LockedBy:    job.LockedBy.String,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		Result:      job.Result,
+	}
+
+	if redactedJobKinds[job.Kind] {
+		response.Payload = nil
+		response.PayloadRedacted = true
+	}
+	if job.LockedUntil.Valid {
+		lockedUntil := job.LockedUntil.Time
+		response.LockedUntil = &lockedUntil
+	}
+	if job.FinishedAt.Valid {
+		finishedAt := job.FinishedAt.Time
+		response.FinishedAt = &finishedAt
+	}
+	if job.UserID.Valid {
+		userID := job.UserID.UUID
+		response.UserID = &userID
+	}
+
+	return response
+}
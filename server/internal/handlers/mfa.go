@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+)
+
+// MFAHandler contains HTTP handlers for two-factor authentication
+type MFAHandler struct {
+	authService *auth.AuthService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(authService *auth.AuthService) *MFAHandler {
+	return &MFAHandler{
+		authService: authService,
+	}
+}
+
+// StatusHandler godoc
+// @Summary      Get two-factor authentication status
+// @Description  Whether the authenticated user has TOTP turned on and how many unused recovery codes they have left
+// @Tags         MFA
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.MFAStatusResponse
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /mfa [get]
+func (h *MFAHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.authService.MFAStatus(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, "mfa status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.MFAStatusResponse{
+		TOTPEnabled:       status.TOTPEnabled,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// EnrollTOTPHandler godoc
+// @Summary      Start TOTP enrollment
+// @Description  Create a TOTP secret after checking the password. Add it to an authenticator app with the otpauth URI or the QR code, then confirm it with a first code. Enrolling again replaces an unconfirmed secret. Accounts created through single sign-on have no password until they set one with a password reset and get 403 Password Not Set until then.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.EnrollTOTPRequest true "Current password"
+// @Success      200 {object} dto.EnrollTOTPResponse
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Invalid Password or Password Not Set"
+// @Failure      409 {string} string "Two-factor authentication is already enabled"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.EnrollTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTOTP(r.Context(), userID, req.Password)
+	if err != nil {
+		writeMFAError(w, "enroll totp", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.EnrollTOTPResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRCodePNG:  enrollment.QRCodePNG,
+	})
+}
+
+// ConfirmTOTPHandler godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Turn on TOTP with a first code from the authenticator app. Returns the recovery codes, which are only shown once. Every other session is signed out and personal access tokens are deleted.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.MFACodeRequest true "TOTP code"
+// @Success      200 {object} dto.RecoveryCodesResponse
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      400 {string} string "Invalid Code"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      409 {string} string "Two-factor authentication is not set up"
+// @Failure      409 {string} string "Two-factor authentication is already enabled"
+// @Failure      429 {string} string "Too Many Attempts"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(r)
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(r.Context(), userID, sessionID, req.Code)
+	if err != nil {
+		writeMFAError(w, "confirm totp", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTOTPHandler godoc
+// @Summary      Turn off TOTP
+// @Description  Turn off two-factor authentication after checking the password and a TOTP or recovery code. The secret and recovery codes are deleted. Accounts created through single sign-on have no password until they set one with a password reset and get 403 Password Not Set until then.
+// @Tags         MFA
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request body dto.DisableTOTPRequest true "Current password and code"
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      400 {string} string "Invalid Code"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Invalid Password or Password Not Set"
+// @Failure      409 {string} string "Two-factor authentication is not set up"
+// @Failure      429 {string} string "Too Many Attempts"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.DisableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DisableTOTP(r.Context(), userID, req.Password, req.Code); err != nil {
+		writeMFAError(w, "disable totp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodesHandler godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes after checking a TOTP code. The old codes stop working and the new ones are only shown once.
+// @Tags         MFA
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.MFACodeRequest true "TOTP code"
+// @Success      200 {object} dto.RecoveryCodesResponse
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      400 {string} string "Invalid Code"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      409 {string} string "Two-factor authentication is not set up"
+// @Failure      429 {string} string "Too Many Attempts"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, "regenerate recovery codes", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// writeMFAError maps errors of the two-factor authentication service to responses
+func writeMFAError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "Invalid Password", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPasswordNotSet):
+		http.Error(w, "Password Not Set, Reset Your Password First", http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		http.Error(w, "Invalid Code", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrMFANotEnrolled):
+		http.Error(w, "Two-factor authentication is not set up", http.StatusConflict)
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+	case errors.Is(err, auth.ErrTooManyRequests):
+		http.Error(w, "Too Many Attempts", http.StatusTooManyRequests)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "User Not Found", http.StatusUnauthorized)
+	default:
+		log.Printf("%s: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+)
+
+const (
+	oidcFlowCookieName = "oidc_flow"
+	oidcCookiePath     = "/api/auth/oidc/"
+
+	// oidcReturnPath is the frontend page single sign-on logins end on
+	oidcReturnPath = "/login/sso"
+)
+
+// OIDCLogin godoc
+// @Summary      Start single sign-on
+// @Description  Redirect the browser to the OpenID Connect provider. The login state is kept in a short-lived HttpOnly cookie until the provider sends the browser back to /auth/oidc/callback.
+// @Tags         Authentication
+// @Success      302 "Redirect to the provider"
+// @Failure      404 {string} string "Single Sign-On Not Configured"
+// @Failure      502 {string} string "Single Sign-On Provider Unavailable"
+// @Router       /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, flowState, err := h.authService.StartOIDCLogin(r.Context())
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCDisabled) {
+			http.Error(w, "Single Sign-On Not Configured", http.StatusNotFound)
+			return
+		}
+		log.Printf("oidc login: %v", err)
+		http.Error(w, "Single Sign-On Provider Unavailable", http.StatusBadGateway)
+		return
+	}
+
+	// Lax, the provider brings the browser back with a top-level navigation
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    flowState,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+		Path:     oidcCookiePath,
+		MaxAge:   int(auth.OIDCFlowTTL.Seconds()),
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary      Finish single sign-on
+// @Description  The OpenID Connect provider redirects here with an authorization code. The browser is sent on to the frontend's /login/sso page with the refresh token cookie set, so the frontend gets an access token from /auth/refresh. Accounts with two-factor authentication get mfa_token and mfa_expires_at in the URL fragment instead, to complete at /auth/login/mfa. Failures carry an error query parameter: access_denied, invalid_state, email_not_verified, no_account, account_conflict or login_failed.
+// @Tags         Authentication
+// @Param        code query string false "Authorization code"
+// @Param        state query string false "State from /auth/oidc/login"
+// @Param        error query string false "Error from the provider"
+// @Success      302 "Redirect to the frontend"
+// @Failure      404 {string} string "Single Sign-On Not Configured"
+// @Router       /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if !h.authService.OIDCEnabled() {
+		http.Error(w, "Single Sign-On Not Configured", http.StatusNotFound)
+		return
+	}
+
+	// The flow state is only good for one attempt
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    "",
+		HttpOnly: true,
+		Expires:  time.Unix(0, 0),
+		Path:     oidcCookiePath,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Printf("oidc callback: provider returned %s: %s", providerErr, query.Get("error_description"))
+		h.redirectOIDCError(w, r, "access_denied")
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookieName)
+	if err != nil {
+		h.redirectOIDCError(w, r, "invalid_state")
+		return
+	}
+
+	result, err := h.authService.CompleteOIDCLogin(r.Context(), cookie.Value, query.Get("state"), query.Get("code"), h.refreshTokenTTL, clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCInvalidState):
+			h.redirectOIDCError(w, r, "invalid_state")
+		case errors.Is(err, auth.ErrOIDCEmailNotVerified):
+			h.redirectOIDCError(w, r, "email_not_verified")
+		case errors.Is(err, auth.ErrOIDCNoAccount):
+			h.redirectOIDCError(w, r, "no_account")
+		case errors.Is(err, auth.ErrOIDCAccountConflict):
+			h.redirectOIDCError(w, r, "account_conflict")
+		default:
+			log.Printf("oidc callback: %v", err)
+			h.redirectOIDCError(w, r, "login_failed")
+		}
+		return
+	}
+
+	// Tokens go in the fragment, which browsers don't send to servers
+	if result.MFARequired() {
+		fragment := url.Values{
+			"mfa_token":      {result.MFAToken},
+			"mfa_expires_at": {strconv.FormatInt(result.MFAExpiresAt.Unix(), 10)},
+		}
+		http.Redirect(w, r, h.appBaseURL+oidcReturnPath+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	h.setRefreshTokenCookie(w, result.RefreshToken)
+	http.Redirect(w, r, h.appBaseURL+oidcReturnPath, http.StatusFound)
+}
+
+// redirectOIDCError sends the browser back to the frontend with the reason a
+// single sign-on login failed
+func (h *AuthHandler) redirectOIDCError(w http.ResponseWriter, r *http.Request, reason string) {
+	http.Redirect(w, r, h.appBaseURL+oidcReturnPath+"?error="+url.QueryEscape(reason), http.StatusFound)
+}
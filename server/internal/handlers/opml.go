@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/opml"
+	"github.com/google/uuid"
+)
+
+// Upper bound for uploaded OPML documents
+const maxOPMLSize = 10 << 20
+
+// OPMLHandler contains HTTP handlers for OPML import and export
+type OPMLHandler struct {
+	opmlService *opml.OPMLService
+}
+
+// NewOPMLHandler creates a new OPML handler
+func NewOPMLHandler(opmlService *opml.OPMLService) *OPMLHandler {
+	return &OPMLHandler{
+		opmlService: opmlService,
+	}
+}
+
+// ImportHandler godoc
+// @Summary      Import subscriptions from OPML
+// @Description  Subscribe the authenticated user to every feed in an OPML 1.0/2.0 document. The document is sent either as the raw request body or as the "file" field of a multipart form. Outline nesting becomes folders and outline titles become custom titles. Documents are imported by the background workers, poll the status endpoint from any instance with the returned ID.
+// @Tags         OPML
+// @Accept       xml
+// @Accept       mpfd
+// @Produce      json
+// @Param        file formData file false "OPML document"
+// @Security     BearerAuth
+// @Success      202 {object} dto.OPMLImportResponse "Import accepted and running in the background"
+// @Failure      400 {string} string "Invalid OPML Document"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      413 {string} string "OPML Document Too Large"
+// @Failure      429 {string} string "Too Many OPML Imports"
+// @Failure      403 {string} string "Email Not Verified"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /opml/import [post]
+func (h *OPMLHandler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// Get the user ID from the context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLSize)
+
+	// Accept both multipart uploads and raw XML bodies
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "OPML Document Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Missing OPML file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	doc, err := opml.Parse(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "OPML Document Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid OPML Document", http.StatusBadRequest)
+		return
+	}
+
+	imp, err := h.opmlService.Import(r.Context(), userID, doc)
+	if err != nil {
+		if errors.Is(err, opml.ErrImportRateLimited) {
+			w.Header().Set("Retry-After", strconv.Itoa(3600))
+			http.Error(w, "Too Many OPML Imports", http.StatusTooManyRequests)
+			return
+		}
+		log.Printf("opml import: failed to enqueue import: %v", err)
+		http.Error(w, "Error Importing OPML", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newOPMLImportResponse(imp))
+}
+
+// ImportStatusHandler godoc
+// @Summary      Get OPML import status
+// @Description  Retrieve the progress and per-outline report of an OPML import started by the authenticated user. Finished imports are kept for a week.
+// @Tags         OPML
+// @Produce      json
+// @Param        id path string true "Import ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.OPMLImportResponse
+// @Failure      400 {string} string "Invalid Import ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Import Not Found"
+// @Router       /opml/import/{id} [get]
+func (h *OPMLHandler) ImportStatusHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	importID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Import ID", http.StatusBadRequest)
+		return
+	}
+
+	imp, err := h.opmlService.GetImport(r.Context(), userID, importID)
+	if err != nil {
+		if errors.Is(err, opml.ErrImportNotFound) {
+			http.Error(w, "Import Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("opml import: failed to load import %s: %v", importID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newOPMLImportResponse(imp))
+}
+
+func newOPMLImportResponse(imp *opml.Import) dto.OPMLImportResponse {
+	results := make([]dto.OPMLImportResult, 0, len(imp.Results))
+	for _, result := range imp.Results {
+		results = append(results, dto.OPMLImportResult{
+			Text:    result.Text,
+			FeedURL: result.FeedURL,
+			Folder:  result.Folder,
+			Status:  string(result.Status),
+			Error:   result.Error,
+		})
+	}
+
+	response := dto.OPMLImportResponse{
+		ID:        imp.ID,
+		Status:    string(imp.Status),
+		Total:     imp.Total,
+		Processed: len(imp.Results),
+		Results:   results,
+		CreatedAt: imp.CreatedAt,
+	}
+
+	if !imp.FinishedAt.IsZero() {
+		finishedAt := imp.FinishedAt
+		response.FinishedAt = &finishedAt
+	}
+
+	return response
+}
+
+// ExportHandler godoc
+// @Summary      Export subscriptions as OPML
+// @Description  Download the authenticated user's subscriptions as an OPML 2.0 document. Folders are exported as nested outlines.
+// @Tags         OPML
+// @Produce      xml
+// @Security     BearerAuth
+// @Success      200 {file} file "OPML document"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /opml/export [get]
+func (h *OPMLHandler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	doc, err := h.opmlService.Export(userID)
+	if err != nil {
+		log.Printf("opml export: failed to build document: %v", err)
+		http.Error(w, "Error Exporting OPML", http.StatusInternalServerError)
+		return
+	}
+
+	// Render into a buffer first so a failure can still produce a proper error response
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		log.Printf("opml export: failed to encode document: %v", err)
+		http.Error(w, "Error Exporting OPML", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("swayamsevak-subscriptions-%s.opml", time.Now().UTC().Format("2006-01-02"))
+
+	w.Header().Set("Content-Type", "text/x-opml+xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
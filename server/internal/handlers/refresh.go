@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// RefreshHandler contains HTTP handlers for on-demand feed refreshes
+type RefreshHandler struct {
+	refreshService *feeds.RefreshService
+}
+
+// NewRefreshHandler creates a new Refresh handler
+func NewRefreshHandler(refreshService *feeds.RefreshService) *RefreshHandler {
+	return &RefreshHandler{
+		refreshService: refreshService,
+	}
+}
+
+// RefreshFeedHandler godoc
+// @Summary      Refresh a feed now
+// @Description  Enqueue an immediate fetch of a feed the authenticated user is subscribed to. Poll the refresh job for the number of new articles.
+// @Tags         Refresh
+// @Produce      json
+// @Param        id path string true "Feed ID"
+// @Security     BearerAuth
+// @Success      202 {object} dto.RefreshJobResponse "Refresh enqueued"
+// @Failure      400 {string} string "Invalid Feed ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Feed Not Found"
+// @Failure      429 {string} string "Too Many Refresh Requests"
+// @Failure      403 {string} string "Email Not Verified"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /feeds/{id}/refresh [post]
+func (h *RefreshHandler) RefreshFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	feedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Feed ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.refreshService.RefreshFeed(r.Context(), userID, feedID)
+	if err != nil {
+		h.writeRefreshError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newRefreshJobResponse(job))
+}
+
+// RefreshSubscriptionsHandler godoc
+// @Summary      Refresh all subscriptions now
+// @Description  Enqueue an immediate fetch of every feed the authenticated user is subscribed to. Poll the refresh job for the number of new articles.
+// @Tags         Refresh
+// @Produce      json
+// @Security     BearerAuth
+// @Success      202 {object} dto.RefreshJobResponse "Refresh enqueued"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      429 {string} string "Too Many Refresh Requests"
+// @Failure      403 {string} string "Email Not Verified"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /subscriptions/refresh [post]
+func (h *RefreshHandler) RefreshSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := h.refreshService.RefreshSubscriptions(r.Context(), userID)
+	if err != nil {
+		h.writeRefreshError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newRefreshJobResponse(job))
+}
+
+// RefreshStatusHandler godoc
+// @Summary      Get refresh job status
+// @Description  Retrieve the status of an on-demand refresh, including new article counts or why feeds failed. Refreshes run on the background workers and can be polled from any instance.
+// @Tags         Refresh
+// @Produce      json
+// @Param        id path string true "Refresh job ID"
+// @Security     BearerAuth
+// @Success      200 {object} dto.RefreshJobResponse
+// @Failure      400 {string} string "Invalid Job ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Refresh Job Not Found"
+// @Router       /refresh/{id} [get]
+func (h *RefreshHandler) RefreshStatusHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.refreshService.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		h.writeRefreshError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newRefreshJobResponse(job))
+}
+
+func (h *RefreshHandler) writeRefreshError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, feeds.ErrRefreshRateLimited):
+		w.Header().Set("Retry-After", strconv.Itoa(60))
+		http.Error(w, "Too Many Refresh Requests", http.StatusTooManyRequests)
+	case errors.Is(err, feeds.ErrNotSubscribedToFeed), errors.Is(err, feeds.ErrFeedNotFound):
+		http.Error(w, "Feed Not Found", http.StatusNotFound)
+	case errors.Is(err, feeds.ErrRefreshJobNotFound):
+		http.Error(w, "Refresh Job Not Found", http.StatusNotFound)
+	default:
+		log.Printf("refresh: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func newRefreshJobResponse(job *feeds.RefreshJob) dto.RefreshJobResponse {
+	results := make([]dto.FeedRefreshResult, 0, len(job.Results))
+	for _, result := range job.Results {
+		results = append(results, dto.FeedRefreshResult{
+			FeedID:      result.FeedID,
+			FeedURL:     result.FeedURL,
+			NewArticles: result.NewArticles,
+			Error:       result.Error,
+		})
+	}
+
+	response := dto.RefreshJobResponse{
+		ID:          job.ID,
+		Status:      string(job.Status),
+		Total:       job.Total,
+		NewArticles: job.NewArticles,
+		Results:     results,
+		CreatedAt:   job.CreatedAt,
+	}
+
+	if !job.FinishedAt.IsZero() {
+		finishedAt := job.FinishedAt
+		response.FinishedAt = &finishedAt
+	}
+
+	return response
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// SessionHandler contains HTTP handlers for managing signed-in devices
+type SessionHandler struct {
+	authService *auth.AuthService
+}
+
+// NewSessionHandler creates a new Session handler
+func NewSessionHandler(authService *auth.AuthService) *SessionHandler {
+	return &SessionHandler{
+		authService: authService,
+	}
+}
+
+// ListSessionsHandler godoc
+// @Summary      List sessions
+// @Description  List the devices the authenticated user is signed in on, most recently used first
+// @Tags         Sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.ListSessionsResponse
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /sessions [get]
+func (h *SessionHandler) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := middleware.GetSessionID(r)
+
+	sessions, err := h.authService.ListSessions(r.Context(), userID)
+	if err != nil {
+		log.Printf("list sessions: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ListSessionsResponse{
+		Sessions: response,
+	})
+}
+
+// RevokeSessionHandler godoc
+// @Summary      Sign out a session
+// @Description  Revoke one of the authenticated user's sessions. Its refresh token stops working immediately.
+// @Tags         Sessions
+// @Param        id path string true "Session ID"
+// @Security     BearerAuth
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid Session ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      404 {string} string "Session Not Found"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /sessions/{id} [delete]
+func (h *SessionHandler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid Session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			http.Error(w, "Session Not Found", http.StatusNotFound)
+			return
+		}
+
+		log.Printf("revoke session: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessionsHandler godoc
+// @Summary      Sign out everywhere else
+// @Description  Revoke every session of the authenticated user except the one making the request
+// @Tags         Sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.RevokeOtherSessionsResponse
+// @Failure      400 {string} string "Access token is not bound to a session, sign in again"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /sessions/revoke-others [post]
+func (h *SessionHandler) RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Without a session we can't tell which one to keep
+	currentID, ok := middleware.GetSessionID(r)
+	if !ok {
+		http.Error(w, "Access token is not bound to a session, sign in again", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(r.Context(), userID, currentID)
+	if err != nil {
+		log.Printf("revoke other sessions: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RevokeOtherSessionsResponse{
+		Revoked: revoked,
+	})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/stream"
+)
+
+// Comments are sent this often so proxies don't close idle streams
+const streamHeartbeatInterval = 20 * time.Second
+
+// Clients reconnect after this many milliseconds when the stream drops
+const streamRetryMillis = 5000
+
+// StreamHandler contains HTTP handlers for the Server-Sent Events article stream
+type StreamHandler struct {
+	streamService *stream.StreamService
+	authService   *auth.AuthService
+}
+
+// NewStreamHandler creates a new Stream handler
+func NewStreamHandler(streamService *stream.StreamService, authService *auth.AuthService) *StreamHandler {
+	return &StreamHandler{
+		streamService: streamService,
+		authService:   authService,
+	}
+}
+
+// StreamTicketHandler godoc
+// @Summary      Get a stream ticket
+// @Description  Issue a ticket valid for one minute to open the article stream with EventSource, which can't send an Authorization header. Pass it as the ticket query parameter of /stream. A ticket opens a single stream, get a new one to reconnect.
+// @Tags         Stream
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} dto.StreamTicketResponse
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /stream/ticket [post]
+func (h *StreamHandler) StreamTicketHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ticket, expiresAt, err := h.authService.GenerateStreamTicket(r.Context(), userID)
+	if err != nil {
+		log.Printf("stream ticket: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// StreamArticlesHandler godoc
+// @Summary      Stream new articles
+// @Description  Server-Sent Events stream of new articles in the user's subscriptions. Each "article" event carries a dto.ArticlesResponse and its id, followed by "unread_count" events (dto.UnreadCountEvent) for the affected feeds. "unread_count" events are also sent when the user marks articles read or unread on any device. Reconnecting with Last-Event-ID resumes after that article. Authenticate with a Bearer token or a ticket from /stream/ticket.
+// @Tags         Stream
+// @Produce      text/event-stream
+// @Param        ticket query string false "Stream ticket, for clients that can't set the Authorization header"
+// @Param        Last-Event-ID header string false "Resume after this event id"
+// @Param        last_event_id query string false "Resume after this event id, for a new EventSource"
+// @Security     BearerAuth
+// @Success      200 {string} string "Event stream"
+// @Failure      400 {string} string "Invalid Last-Event-ID"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /stream [get]
+func (h *StreamHandler) StreamArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lastSeq := int64(-1)
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastSeq = seq
+	}
+
+	ctx := r.Context()
+
+	st, err := h.streamService.Open(ctx, userID, lastSeq)
+	if err != nil {
+		log.Printf("open stream: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer st.Close()
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("stream: can't clear the write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Ask nginx and similar proxies not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetryMillis)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-st.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-st.Wake():
+			articles, unread, err := st.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("stream: failed to load new articles: %v", err)
+				}
+				return
+			}
+
+			for _, article := range articles {
+				writeStreamEvent(w, strconv.FormatInt(article.Seq, 10), "article", dto.ArticlesResponse{
+					ID:          article.ID,
+					FeedID:      article.FeedID,
+					GUID:        article.GUID,
+					Title:       article.Title,
+					URL:         article.URL,
+					Author:      article.Author,
+					Content:     article.Content,
+					Summary:     article.Summary,
+					PublishedAt: article.PublishedAt,
+					CreatedAt:   article.CreatedAt,
+					UpdatedAt:   article.UpdatedAt,
+				})
+			}
+			for feedID, count := range unread {
+				writeStreamEvent(w, "", "unread_count", dto.UnreadCountEvent{
+					FeedID: feedID,
+					Unread: count,
+				})
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeStreamEvent writes a single SSE event, id is omitted when empty so the
+// client keeps its last event id
+func writeStreamEvent(w http.ResponseWriter, id, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("stream: failed to encode %s event: %v", event, err)
+		return
+	}
+
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
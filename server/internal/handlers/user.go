@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers/dto"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
 	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
@@ -11,13 +15,15 @@ import (
 
 // UserHandler contains HTTP handlers for user-related endpoints
 type UserHandler struct {
-	userRepo *models.UserRepository
+	userRepo    *models.UserRepository
+	authService *auth.AuthService
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userRepo *models.UserRepository) *UserHandler {
+func NewUserHandler(userRepo *models.UserRepository, authService *auth.AuthService) *UserHandler {
 	return &UserHandler{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		authService: authService,
 	}
 }
 
@@ -49,13 +55,211 @@ func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return User Profile
-	response := dto.UserResponse{
+	response := toUserResponse(user)
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// toUserResponse converts a user to its API representation
+func toUserResponse(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
 		ID:       user.ID.String(),
 		Email:    user.Email,
 		Username: user.Username,
+
+		EmailVerified: user.EmailVerified(),
+		PendingEmail:  user.PendingEmail.String,
+		HasPassword:   user.HasPassword(),
+		Role:          user.Role,
 	}
+}
 
-	w.Header().Set("Content-type", "application/json")
+// UpdateProfile godoc
+// @Summary      Update user profile
+// @Description  Change the authenticated user's username
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.UpdateProfileRequest true "New profile values"
+// @Success      200 {object} dto.UserResponse
+// @Failure      400 {string} string "Invalid Username"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      409 {string} string "Username already in use"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /profile [patch]
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.UpdateProfile(r.Context(), userID, req.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidUsername):
+			http.Error(w, "Invalid Username", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrUsernameInUse):
+			http.Error(w, "Username already in use", http.StatusConflict)
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "User Not Found", http.StatusUnauthorized)
+		default:
+			log.Printf("update profile: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(toUserResponse(user))
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Replace the authenticated user's password. The current password is required, every other session is signed out and personal access tokens are deleted. Accounts created through single sign-on have no password until they set one with a password reset and get 403 Password Not Set until then.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.ChangePasswordRequest true "Current and new password"
+// @Success      200 {object} dto.ChangePasswordResponse
+// @Failure      400 {string} string "Password must be between 8 and 72 bytes long"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Invalid Password or Password Not Set"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /profile/password [post]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(r)
+
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.authService.ChangePassword(r.Context(), userID, sessionID, req.CurrentPassword, req.NewPassword, clientInfo(r))
+	if err != nil {
+		writeAccountError(w, "change password", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ChangePasswordResponse{
+		RevokedSessions: revoked,
+	})
+}
+
+// ChangeEmail godoc
+// @Summary      Change email address
+// @Description  Ask to move the authenticated user's account to a new email address. The password is required. The new address is pending and only replaces the current one once the link emailed to it is followed. Accounts created through single sign-on have no password until they set one with a password reset and get 403 Password Not Set until then.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.ChangeEmailRequest true "New email and password"
+// @Success      200 {object} dto.UserResponse
+// @Failure      400 {string} string "Invalid Email Address"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Invalid Password or Password Not Set"
+// @Failure      409 {string} string "Email already in use"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /profile/email [put]
+func (h *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(r)
+
+	var req dto.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.ChangeEmail(r.Context(), userID, sessionID, req.Password, req.Email, clientInfo(r))
+	if err != nil {
+		writeAccountError(w, "change email", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toUserResponse(user))
+}
+
+// DeleteAccount godoc
+// @Summary      Delete account
+// @Description  Permanently delete the authenticated user's account with its sessions, subscriptions and article states. The password is required. Feeds are shared and are not deleted. Accounts created through single sign-on have no password until they set one with a password reset and get 403 Password Not Set until then.
+// @Tags         Users
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request body dto.DeleteAccountRequest true "Password"
+// @Success      204 "No Content"
+// @Failure      400 {string} string "Invalid Request Payload"
+// @Failure      401 {string} string "Unauthorized"
+// @Failure      403 {string} string "Invalid Password or Password Not Set"
+// @Failure      409 {string} string "Cannot delete the last admin"
+// @Failure      500 {string} string "Internal Server Error"
+// @Router       /profile [delete]
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid Request Payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.DeleteAccount(r.Context(), userID, req.Password); err != nil {
+		writeAccountError(w, "delete account", err)
+		return
+	}
+
+	clearRefreshTokenCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAccountError maps errors of account changes to responses
+func writeAccountError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "Invalid Password", http.StatusForbidden)
+	case errors.Is(err, auth.ErrPasswordNotSet):
+		http.Error(w, "Password Not Set, Reset Your Password First", http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidPassword):
+		http.Error(w, "Password must be between 8 and 72 bytes long", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrInvalidEmail):
+		http.Error(w, "Invalid Email Address", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrEmailInUse):
+		http.Error(w, "Email already in use", http.StatusConflict)
+	case errors.Is(err, models.ErrLastAdmin):
+		http.Error(w, "Cannot delete the last admin", http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "User Not Found", http.StatusUnauthorized)
+	default:
+		log.Printf("%s: %v", action, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
 }
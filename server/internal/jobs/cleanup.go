@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// KindCleanupJobs removes finished jobs once they are no longer useful for inspection
+const KindCleanupJobs = "jobs.cleanup"
+
+// CleanupPayload configures the jobs.cleanup job
+type CleanupPayload struct {
+	// RetainFor is how long completed and dead jobs are kept, e.g. "168h"
+	RetainFor string `json:"retain_for"`
+}
+
+// RegisterCleanup registers the recurring cleanup of finished jobs
+func (s *JobService) RegisterCleanup(interval, retainFor time.Duration) {
+	Register(s, KindCleanupJobs, func(ctx context.Context, payload CleanupPayload) error {
+		retain, err := time.ParseDuration(payload.RetainFor)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		deleted, err := s.DeleteFinishedJobs(ctx, retain)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Deleted %d finished jobs", deleted)
+		return nil
+	})
+
+	s.Every(KindCleanupJobs, interval, CleanupPayload{RetainFor: retainFor.String()})
+}
@@ -0,0 +1,312 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobNotRetryable  = errors.New("only dead or pending jobs can be retried")
+	ErrJobAlreadyQueued = errors.New("another unfinished job holds the unique key")
+	ErrUnknownJobKind   = errors.New("no handler registered for job kind")
+	ErrRateLimited      = errors.New("too many jobs requested")
+	ErrLeaseLost        = errors.New("job is no longer leased to this worker")
+)
+
+// Jobs are attempted this many times unless EnqueueOptions says otherwise
+const defaultMaxAttempts = 5
+
+// Handler runs a single job. Returning an error schedules a retry with backoff,
+// wrap it with Permanent to move the job straight to the dead state instead.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// EnqueueOptions tunes how a job is queued
+type EnqueueOptions struct {
+	// RunAt delays the job, the zero value runs it as soon as possible
+	RunAt time.Time
+	// UniqueKey prevents enqueueing a job while another unfinished job holds the same key
+	UniqueKey string
+	// MaxAttempts defaults to 5
+	MaxAttempts int
+}
+
+// schedule describes a recurring job
+type schedule struct {
+	interval time.Duration
+	payload  any
+}
+
+// JobService enqueues jobs and holds the handlers and recurring schedules the
+// Worker executes. Every process registers the same handlers so any of them can
+// enqueue work while only worker processes run it.
+type JobService struct {
+	jobRepo *models.JobRepository
+
+	mu        sync.RWMutex
+	handlers  map[string]Handler
+	schedules map[string]schedule
+}
+
+// NewJobService creates a new job service
+func NewJobService(jobRepo *models.JobRepository) *JobService {
+	return &JobService{
+		jobRepo:   jobRepo,
+		handlers:  make(map[string]Handler),
+		schedules: make(map[string]schedule),
+	}
+}
+
+// Handle registers the handler for a job kind
+func (s *JobService) Handle(kind string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[kind] = handler
+}
+
+// Register registers a typed handler for a job kind, decoding the JSON payload into T
+func Register[T any](s *JobService, kind string, fn func(ctx context.Context, payload T) error) {
+	s.Handle(kind, func(ctx context.Context, job *models.Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(fmt.Errorf("decoding %s payload: %w", kind, err))
+		}
+
+		return fn(ctx, payload)
+	})
+}
+
+// Every runs a registered job kind repeatedly, interval after the previous run finished
+func (s *JobService) Every(kind string, interval time.Duration, payload any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[kind] = schedule{
+		interval: interval,
+		payload:  payload,
+	}
+}
+
+// Enqueue adds a job to the queue. With a UniqueKey that is already held by an
+// unfinished job, the existing job is returned and nothing new is queued.
+func (s *JobService) Enqueue(ctx context.Context, kind string, payload any, opts EnqueueOptions) (*models.Job, error) {
+	if _, ok := s.handler(kind); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobKind, kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	job, _, err := s.jobRepo.CreateJob(ctx, kind, data, runAt, opts.UniqueKey, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// EnqueueForUser adds a job on behalf of a user, who may enqueue at most limit
+// jobs of the kind per window. Beyond that it returns ErrRateLimited. The limit
+// is enforced in the database, so it holds across instances.
+func (s *JobService) EnqueueForUser(ctx context.Context, userID uuid.UUID, kind string, payload any, maxAttempts, limit int, window time.Duration) (*models.Job, error) {
+	if _, ok := s.handler(kind); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobKind, kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	job, err := s.jobRepo.CreateUserJob(ctx, userID, kind, data, maxAttempts, limit, time.Now().Add(-window))
+	if err != nil {
+		if errors.Is(err, models.ErrJobLimitReached) {
+			return nil, ErrRateLimited
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetUserJob retrieves a job of the given kind requested by the user
+func (s *JobService) GetUserJob(ctx context.Context, userID uuid.UUID, kind string, id uuid.UUID) (*models.Job, error) {
+	job, err := s.jobRepo.GetUserJob(ctx, userID, kind, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// SetResult stores result as what the job reports back, handlers may call it
+// repeatedly to report progress
+func (s *JobService) SetResult(ctx context.Context, id uuid.UUID, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return s.jobRepo.UpdateJobResult(ctx, id, data)
+}
+
+// GetJob retrieves a job by its ID
+func (s *JobService) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, err := s.jobRepo.GetJobByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ListJobs retrieves jobs newest first, optionally filtered by status and kind
+func (s *JobService) ListJobs(ctx context.Context, status, kind string, offset, limit int) ([]*models.Job, error) {
+	return s.jobRepo.ListJobs(ctx, status, kind, offset, limit)
+}
+
+// RetryJob re-queues a dead or pending job to run immediately. A dead job whose
+// unique key is held by a newer run, e.g. of a recurring job, fails with
+// ErrJobAlreadyQueued.
+func (s *JobService) RetryJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job, err := s.jobRepo.RetryJob(ctx, id)
+	if err == nil {
+		return job, nil
+	}
+	if errors.Is(err, models.ErrJobKeyHeld) {
+		return nil, ErrJobAlreadyQueued
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Tell a missing job apart from one in the wrong state
+	if _, err := s.GetJob(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrJobNotRetryable
+}
+
+// DeleteFinishedJobs removes completed and dead jobs that finished more than olderThan ago
+func (s *JobService) DeleteFinishedJobs(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return s.jobRepo.DeleteFinishedJobs(ctx, time.Now().Add(-olderThan))
+}
+
+func (s *JobService) handler(kind string) (Handler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	handler, ok := s.handlers[kind]
+	return handler, ok
+}
+
+// kinds lists the registered job kinds in a stable order
+func (s *JobService) kinds() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kinds := make([]string, 0, len(s.handlers))
+	for kind := range s.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+func (s *JobService) schedule(kind string) (schedule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sched, ok := s.schedules[kind]
+	return sched, ok
+}
+
+// scheduleNext queues the next run of a recurring job after delay. The
+// per-kind unique key keeps instances from queueing the same run twice.
+func (s *JobService) scheduleNext(ctx context.Context, kind string, delay time.Duration) error {
+	sched, ok := s.schedule(kind)
+	if !ok {
+		return nil
+	}
+
+	_, err := s.Enqueue(ctx, kind, sched.payload, EnqueueOptions{
+		RunAt:     time.Now().Add(delay),
+		UniqueKey: recurringKey(kind),
+	})
+	return err
+}
+
+// ensureSchedules queues every recurring job that has no pending run, e.g. on
+// first start or after a worker died between finishing a run and queueing the next
+func (s *JobService) ensureSchedules(ctx context.Context) error {
+	s.mu.RLock()
+	kinds := make([]string, 0, len(s.schedules))
+	for kind := range s.schedules {
+		kinds = append(kinds, kind)
+	}
+	s.mu.RUnlock()
+
+	for _, kind := range kinds {
+		if err := s.scheduleNext(ctx, kind, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recurringKey(kind string) string {
+	return "recurring:" + kind
+}
+
+// permanentError marks a failure that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is moved to the dead state without retries
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,271 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// Retries back off exponentially from retryBaseDelay up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+
+	// How often recurring schedules are re-checked
+	ensureSchedulesInterval = time.Minute
+)
+
+// WorkerConfig controls how the Worker runs jobs
+type WorkerConfig struct {
+	// Concurrency is the number of jobs run at once
+	Concurrency int
+	// PollInterval is how often the queue is polled for runnable jobs
+	PollInterval time.Duration
+	// Lease is how long a claimed job may run before other workers reclaim it
+	Lease time.Duration
+}
+
+// Worker claims and runs jobs registered on the JobService
+type Worker struct {
+	jobService *JobService
+	cfg        WorkerConfig
+	owner      string
+}
+
+// NewWorker creates a new job worker
+func NewWorker(jobService *JobService, cfg WorkerConfig) *Worker {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &Worker{
+		jobService: jobService,
+		cfg:        cfg,
+		owner:      fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+// Start runs jobs until ctx is cancelled, then waits for running jobs to return
+func (w *Worker) Start(ctx context.Context) {
+	log.Printf("Running jobs on %v goroutines", w.cfg.Concurrency)
+
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	poll := time.NewTicker(w.cfg.PollInterval)
+	defer poll.Stop()
+
+	var lastEnsure time.Time
+
+	for {
+		if time.Since(lastEnsure) >= ensureSchedulesInterval {
+			if err := w.jobService.ensureSchedules(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error scheduling recurring jobs: %v", err)
+			}
+			lastEnsure = time.Now()
+		}
+
+		if free := cap(sem) - len(sem); free > 0 {
+			kinds := w.jobService.kinds()
+			if len(kinds) > 0 {
+				jobs, err := w.jobService.jobRepo.ClaimJobs(ctx, w.owner, kinds, free, w.cfg.Lease)
+				if err != nil && ctx.Err() == nil {
+					log.Printf("Error claiming jobs: %v", err)
+				}
+
+				for _, job := range jobs {
+					sem <- struct{}{}
+					wg.Add(1)
+
+					go func(job *models.Job) {
+						defer wg.Done()
+						defer func() {
+							<-sem
+						}()
+
+						w.run(ctx, job)
+					}(job)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Job Worker Shutting Down")
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+// run executes a job and records its outcome
+func (w *Worker) run(ctx context.Context, job *models.Job) {
+	err := w.execute(ctx, job)
+
+	// Record the outcome even if the worker is shutting down
+	ctx = context.WithoutCancel(ctx)
+
+	switch nextStatus(job, err) {
+	case models.JobStatusCompleted:
+		if err := w.jobService.jobRepo.CompleteJob(ctx, job.ID, w.owner); err != nil {
+			log.Printf("Error completing job %s (%s): %v", job.ID, job.Kind, err)
+		}
+		w.reschedule(ctx, job)
+	case models.JobStatusDead:
+		log.Printf("Job %s (%s) is dead after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+		if err := w.jobService.jobRepo.KillJob(ctx, job.ID, w.owner, err.Error()); err != nil {
+			log.Printf("Error killing job %s (%s): %v", job.ID, job.Kind, err)
+		}
+		w.reschedule(ctx, job)
+	default:
+		retryAt := time.Now().Add(backoff(job.Attempts))
+		log.Printf("Job %s (%s) failed on attempt %d, retrying at %v: %v", job.ID, job.Kind, job.Attempts, retryAt.Format(time.RFC3339), err)
+		if err := w.jobService.jobRepo.RetryJobLater(ctx, job.ID, w.owner, err.Error(), retryAt); err != nil {
+			log.Printf("Error rescheduling job %s (%s): %v", job.ID, job.Kind, err)
+		}
+	}
+}
+
+// nextStatus returns the status a claimed job moves to after an attempt that
+// returned err. Failed jobs go back to pending until their attempts run out.
+func nextStatus(job *models.Job, err error) string {
+	switch {
+	case err == nil:
+		return models.JobStatusCompleted
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		return models.JobStatusDead
+	default:
+		return models.JobStatusPending
+	}
+}
+
+// execute runs the job's handler within the lease, turning panics into errors.
+// Handlers that need longer than the lease extend it with ExtendLease.
+func (w *Worker) execute(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := w.jobService.handler(job.Kind)
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrUnknownJobKind, job.Kind))
+	}
+
+	ctx, l := w.newLease(ctx, job)
+	defer l.stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// leaseKey stores the running job's lease in the handler's context
+type leaseKey struct{}
+
+// lease cancels a running job's context once its lock in the jobs table expires
+type lease struct {
+	jobRepo *models.JobRepository
+	id      uuid.UUID
+	owner   string
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (w *Worker) newLease(ctx context.Context, job *models.Job) (context.Context, *lease) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	l := &lease{
+		jobRepo: w.jobService.jobRepo,
+		id:      job.ID,
+		owner:   w.owner,
+		cancel:  cancel,
+	}
+	l.timer = time.AfterFunc(w.cfg.Lease, cancel)
+
+	return context.WithValue(ctx, leaseKey{}, l), l
+}
+
+func (l *lease) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.timer.Stop()
+	l.cancel()
+}
+
+// ExtendLease keeps the job running in ctx locked to this worker for d from now,
+// so other workers don't reclaim it and its context isn't cancelled meanwhile.
+// Outside a job it does nothing.
+func ExtendLease(ctx context.Context, d time.Duration) error {
+	l, ok := ctx.Value(leaseKey{}).(*lease)
+	if !ok {
+		return nil
+	}
+
+	extended, err := l.jobRepo.ExtendJobLease(ctx, l.id, l.owner, d)
+	if err != nil {
+		return err
+	}
+	if !extended {
+		return ErrLeaseLost
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ctx.Err() == nil {
+		l.timer.Reset(d)
+	}
+	return nil
+}
+
+// reschedule queues the next run of recurring jobs once the current one has finished
+func (w *Worker) reschedule(ctx context.Context, job *models.Job) {
+	if !job.UniqueKey.Valid || job.UniqueKey.String != recurringKey(job.Kind) {
+		return
+	}
+
+	sched, ok := w.jobService.schedule(job.Kind)
+	if !ok {
+		return
+	}
+
+	if err := w.jobService.scheduleNext(ctx, job.Kind, sched.interval); err != nil {
+		log.Printf("Error scheduling next run of %s: %v", job.Kind, err)
+	}
+}
+
+// backoff returns the delay before the given attempt is retried, with jitter
+func backoff(attempt int) time.Duration {
+	attempt = max(attempt, 1)
+
+	delay := retryMaxDelay
+	if attempt < 12 {
+		delay = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+
+	// +/- 20% so failing jobs don't retry in lockstep
+	jitter := time.Duration(rand.Int64N(int64(delay)*2/5)) - delay/5
+	return delay + jitter
+}
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestNextStatus(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		attempts    int
+		maxAttempts int
+		err         error
+		want        string
+	}{
+		{"succeeded", 1, 5, nil, models.JobStatusCompleted},
+		{"succeeded on last attempt", 5, 5, nil, models.JobStatusCompleted},
+		{"failed", 1, 5, failure, models.JobStatusPending},
+		{"failed before last attempt", 4, 5, failure, models.JobStatusPending},
+		{"failed on last attempt", 5, 5, failure, models.JobStatusDead},
+		{"failed past last attempt", 6, 5, failure, models.JobStatusDead},
+		{"single attempt", 1, 1, failure, models.JobStatusDead},
+		{"permanent", 1, 5, Permanent(failure), models.JobStatusDead},
+		{"wrapped permanent", 1, 5, fmt.Errorf("sending: %w", Permanent(failure)), models.JobStatusDead},
+		{"lease expired", 1, 5, context.Canceled, models.JobStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{Attempts: tt.attempts, MaxAttempts: tt.maxAttempts}
+			if got := nextStatus(job, tt.err); got != tt.want {
+				t.Errorf("nextStatus(attempt %d of %d, %v) = %q, want %q", tt.attempts, tt.maxAttempts, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	jobService := NewJobService(nil)
+	jobService.Handle("ok", func(ctx context.Context, job *models.Job) error {
+		return nil
+	})
+	jobService.Handle("fail", func(ctx context.Context, job *models.Job) error {
+		return errors.New("temporary failure")
+	})
+	jobService.Handle("permanent", func(ctx context.Context, job *models.Job) error {
+		return Permanent(errors.New("bad input"))
+	})
+	jobService.Handle("panic", func(ctx context.Context, job *models.Job) error {
+		panic("boom")
+	})
+	jobService.Handle("slow", func(ctx context.Context, job *models.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	Register(jobService, "typed", func(ctx context.Context, payload struct {
+		Count int `json:"count"`
+	}) error {
+		if payload.Count != 1 {
+			return fmt.Errorf("count = %d", payload.Count)
+		}
+		return nil
+	})
+
+	worker := NewWorker(jobService, WorkerConfig{Lease: 20 * time.Millisecond})
+
+	tests := []struct {
+		name     string
+		kind     string
+		payload  string
+		attempts int
+		want     string
+	}{
+		{"succeeds", "ok", `{}`, 1, models.JobStatusCompleted},
+		{"retried", "fail", `{}`, 1, models.JobStatusPending},
+		{"out of attempts", "fail", `{}`, 3, models.JobStatusDead},
+		{"permanent failure", "permanent", `{}`, 1, models.JobStatusDead},
+		{"panic is retried", "panic", `{}`, 1, models.JobStatusPending},
+		{"lease expires", "slow", `{}`, 1, models.JobStatusPending},
+		{"unknown kind", "missing", `{}`, 1, models.JobStatusDead},
+		{"typed payload", "typed", `{"count":1}`, 1, models.JobStatusCompleted},
+		{"malformed typed payload", "typed", `{"count":"one"}`, 1, models.JobStatusDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{
+				ID:          uuid.New(),
+				Kind:        tt.kind,
+				Payload:     json.RawMessage(tt.payload),
+				Status:      models.JobStatusRunning,
+				Attempts:    tt.attempts,
+				MaxAttempts: 3,
+			}
+
+			err := worker.execute(context.Background(), job)
+			if got := nextStatus(job, err); got != tt.want {
+				t.Errorf("%s job with error %v moves to %q, want %q", tt.kind, err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteUnknownKind(t *testing.T) {
+	worker := NewWorker(NewJobService(nil), WorkerConfig{})
+
+	err := worker.execute(context.Background(), &models.Job{Kind: "missing"})
+	if !errors.Is(err, ErrUnknownJobKind) || !isPermanent(err) {
+		t.Errorf("execute = %v, want a permanent ErrUnknownJobKind", err)
+	}
+}
+
+func TestExtendLeaseOutsideJob(t *testing.T) {
+	if err := ExtendLease(context.Background(), time.Minute); err != nil {
+		t.Errorf("ExtendLease outside a job = %v, want nil", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, retryBaseDelay},
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{3, 4 * retryBaseDelay},
+		{7, 64 * retryBaseDelay},
+		{8, retryMaxDelay},
+		{12, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			low, high := tt.want*4/5, tt.want*6/5
+			for range 100 {
+				if got := backoff(tt.attempt); got < low || got > high {
+					t.Fatalf("backoff(%d) = %v, want within %v and %v", tt.attempt, got, low, high)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package mail
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	const from = "Swayamsevak <noreply@example.com>"
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want Mailer
+	}{
+		{"smtp", Config{Driver: "smtp", From: from, SMTPHost: "smtp.example.com"}, &SMTPMailer{}},
+		{"smtp in development", Config{Driver: "smtp", From: from, SMTPHost: "smtp.example.com", Development: true}, &SMTPMailer{}},
+		{"file", Config{Driver: "file", From: from}, &FileMailer{}},
+		{"log in development", Config{Driver: "log", From: from, Development: true}, &LogMailer{}},
+		{"unset in development", Config{From: from, Development: true}, &LogMailer{}},
+		{"unset in production", Config{From: from}, &DiscardMailer{}},
+		{"bare sender address", Config{From: "noreply@example.com"}, &DiscardMailer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.Driver == "file" {
+				tt.cfg.Dir = t.TempDir()
+			}
+
+			got, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New(%+v) error: %v", tt.cfg, err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("New(%+v) = %T, want %T", tt.cfg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	const from = "noreply@example.com"
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"smtp without host", Config{Driver: "smtp", From: from}},
+		{"log in production", Config{Driver: "log", From: from}},
+		{"unknown driver", Config{Driver: "sendmail", From: from, Development: true}},
+		{"driver case", Config{Driver: "SMTP", From: from, SMTPHost: "smtp.example.com"}},
+		{"missing sender", Config{Driver: "log", Development: true}},
+		{"invalid sender", Config{Driver: "log", From: "noreply", Development: true}},
+		{"invalid sender without driver", Config{From: "not an address"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := New(tt.cfg); err == nil {
+				t.Errorf("New(%+v) = %T, want an error", tt.cfg, got)
+			}
+		})
+	}
+}
+
+func TestNewFileMailerCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "mail")
+
+	if _, err := New(Config{Driver: "file", From: "noreply@example.com", Dir: dir}); err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if matches, _ := filepath.Glob(dir); len(matches) != 1 {
+		t.Errorf("New didn't create %s", dir)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		msg     Message
+		headers []string
+		body    string
+	}{
+		{
+			"plain",
+			Message{To: "user@example.com", Subject: "Verify your email", Text: "Hello\nworld"},
+			[]string{"From: noreply@example.com", "To: user@example.com", "Subject: Verify your email", "Date: Fri, 02 Jan 2026 03:04:05 +0000"},
+			"Hello\r\nworld",
+		},
+		{
+			"utf-8 subject and body",
+			Message{To: "user@example.com", Subject: "Café", Text: "Grüße"},
+			[]string{"Subject: =?utf-8?q?Caf=C3=A9?="},
+			"Gr=C3=BC=C3=9Fe",
+		},
+		{
+			"newline in subject",
+			Message{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+			[]string{"Subject: =?utf-8?q?Hi=0ABcc:_victim@example.com?="},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compose("noreply@example.com", tt.msg, now)
+			if err != nil {
+				t.Fatalf("compose error: %v", err)
+			}
+
+			head, body, ok := bytes.Cut(got, []byte("\r\n\r\n"))
+			if !ok {
+				t.Fatalf("compose(%+v) has no header/body separator:\n%s", tt.msg, got)
+			}
+			for _, h := range tt.headers {
+				if !strings.Contains(string(head)+"\r\n", h+"\r\n") {
+					t.Errorf("headers missing %q:\n%s", h, head)
+				}
+			}
+			if !strings.Contains(string(head), "Message-ID: <") || !strings.Contains(string(head), "@example.com>") {
+				t.Errorf("headers missing a Message-ID on the sender's domain:\n%s", head)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestComposeHeaderInjection(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		{To: "user@example.com\nBcc: victim@example.com", Subject: "Hi"},
+	} {
+		if got, err := compose("noreply@example.com", msg, time.Now()); err == nil {
+			t.Errorf("compose(%+v) = %q, want an error", msg, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/secretbox"
+)
+
+// KindSendMail delivers a single email
+const KindSendMail = "mail.send"
+
+// Binds sealed messages to the mail queue
+var sealedMessageAD = []byte(KindSendMail)
+
+// sealedMessage is the mail.send payload. Emails carry verification and reset
+// links, so the message is only stored encrypted in the jobs table.
+type sealedMessage struct {
+	Sealed []byte `json:"sealed"`
+}
+
+// RegisterDelivery registers the mail.send job, delivering queued emails through mailer
+func RegisterDelivery(jobService *jobs.JobService, mailer Mailer, box *secretbox.Box) {
+	jobs.Register(jobService, KindSendMail, func(ctx context.Context, payload sealedMessage) error {
+		plaintext, err := box.Open(payload.Sealed, sealedMessageAD)
+		if err != nil {
+			// Sealed with another key, retrying won't help
+			return jobs.Permanent(err)
+		}
+
+		var msg Message
+		if err := json.Unmarshal(plaintext, &msg); err != nil {
+			return jobs.Permanent(err)
+		}
+
+		return mailer.Send(ctx, msg)
+	})
+}
+
+// QueuedMailer sends emails from the background jobs, so requests don't wait
+// on the mail server and failed deliveries are retried
+type QueuedMailer struct {
+	jobService *jobs.JobService
+	box        *secretbox.Box
+}
+
+// NewQueuedMailer creates a mailer enqueueing mail.send jobs sealed with box,
+// which must be registered with RegisterDelivery using the same key
+func NewQueuedMailer(jobService *jobs.JobService, box *secretbox.Box) *QueuedMailer {
+	return &QueuedMailer{
+		jobService: jobService,
+		box:        box,
+	}
+}
+
+// Send queues msg for delivery
+func (m *QueuedMailer) Send(ctx context.Context, msg Message) error {
+	plaintext, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := m.box.Seal(plaintext, sealedMessageAD)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.jobService.Enqueue(ctx, KindSendMail, sealedMessage{Sealed: sealed}, jobs.EnqueueOptions{})
+	return err
+}
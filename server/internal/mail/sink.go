@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every email to its own .eml file instead of sending it,
+// for local development and tests
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a mailer writing to dir, creating it if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if dir == "" {
+		dir = "mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes msg to a file named after the time it was sent
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+
+	body, err := compose(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(m.dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote email %q for %s to %s", msg.Subject, msg.To, filepath.Base(f.Name()))
+	return nil
+}
+
+// LogMailer prints emails to the log instead of sending them
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a mailer that logs every email
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{
+		from: from,
+	}
+}
+
+// Send logs msg including its body, so links in it can be followed locally
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email from %s to %s: %s\n%s", m.from, msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// DiscardMailer drops every email, for deployments without MAIL_DRIVER
+type DiscardMailer struct{}
+
+// NewDiscardMailer creates a mailer that drops emails, warning that none are sent
+func NewDiscardMailer() *DiscardMailer {
+	log.Println("WARNING: MAIL_DRIVER is not set, emails such as verification and password reset links are dropped")
+	return &DiscardMailer{}
+}
+
+// Send drops msg, logging only who it was for so links stay out of the log
+func (m *DiscardMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("WARNING: dropped email %q for %s, MAIL_DRIVER is not set", msg.Subject, msg.To)
+	return nil
+}
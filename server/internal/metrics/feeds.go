@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Scraping gives up on the database queries after this long
+const storeStatsTimeout = 5 * time.Second
+
+// ObserveFetch records the outcome and duration of a feed fetch and how many articles it stored
+func ObserveFetch(outcome string, duration time.Duration, inserted int64) {
+	feedFetches.WithLabelValues(outcome).Inc()
+	feedFetchDuration.Observe(duration.Seconds())
+	if inserted > 0 {
+		articlesInserted.Add(float64(inserted))
+	}
+}
+
+// SetFetchQueueDepth records how many claimed feeds are waiting for a fetcher
+func SetFetchQueueDepth(depth int) {
+	fetchQueueDepth.Set(float64(depth))
+}
+
+// RegisterFeedStats exports feed, subscription and scheduler lag gauges, queried
+// from the database on every scrape. A feed is overdue once it hasn't been
+// fetched for fetchInterval.
+func RegisterFeedStats(feedRepo *models.FeedRepository, fetchInterval time.Duration) {
+	registry.MustRegister(&feedStatsCollector{
+		feedRepo:      feedRepo,
+		fetchInterval: fetchInterval,
+	})
+}
+
+var (
+	feedsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "feeds"),
+		"Feeds stored.", nil, nil)
+	subscribedFeedsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "subscribed_feeds"),
+		"Feeds with at least one subscriber, only these are fetched.", nil, nil)
+	subscriptionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "subscriptions"),
+		"Active feed subscriptions.", nil, nil)
+	overdueFeedsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "feed_scheduler", "overdue_feeds"),
+		"Subscribed feeds due for a fetch that haven't been fetched yet.", nil, nil)
+	schedulerLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "feed_scheduler", "lag_seconds"),
+		"How long the oldest overdue feed has been due, 0 when none is overdue.", nil, nil)
+)
+
+// feedStatsCollector queries feed and subscription counts at scrape time
+type feedStatsCollector struct {
+	feedRepo      *models.FeedRepository
+	fetchInterval time.Duration
+}
+
+func (c *feedStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- feedsDesc
+	ch <- subscribedFeedsDesc
+	ch <- subscriptionsDesc
+	ch <- overdueFeedsDesc
+	ch <- schedulerLagDesc
+}
+
+func (c *feedStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeStatsTimeout)
+	defer cancel()
+
+	stats, err := c.feedRepo.GetFeedStats(ctx, c.fetchInterval)
+	if err != nil {
+		log.Printf("metrics: failed to query feed stats: %v", err)
+		ch <- prometheus.NewInvalidMetric(feedsDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(feedsDesc, prometheus.GaugeValue, float64(stats.Feeds))
+	ch <- prometheus.MustNewConstMetric(subscribedFeedsDesc, prometheus.GaugeValue, float64(stats.SubscribedFeeds))
+	ch <- prometheus.MustNewConstMetric(subscriptionsDesc, prometheus.GaugeValue, float64(stats.Subscriptions))
+	ch <- prometheus.MustNewConstMetric(overdueFeedsDesc, prometheus.GaugeValue, float64(stats.OverdueFeeds))
+	ch <- prometheus.MustNewConstMetric(schedulerLagDesc, prometheus.GaugeValue, stats.Lag.Seconds())
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+)
+
+// RequireRole only lets users holding one of roles through, it must be wrapped by
+// AuthMiddleware. The role claim of the access token rejects early, but the role
+// stored for the user decides, so demotions apply before the token expires while
+// promotions apply from the next token refresh.
+func RequireRole(userRepo *models.UserRepository, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := requireRole(w, r, userRepo, roles); ok {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RequireAdmin only lets administrators through, it must be wrapped by AuthMiddleware.
+// With requireMFA they must also have two-factor authentication turned on.
+func RequireAdmin(userRepo *models.UserRepository, requireMFA bool) func(http.Handler) http.Handler {
+	roles := []string{models.RoleAdmin}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := requireRole(w, r, userRepo, roles)
+			if !ok {
+				return
+			}
+
+			if requireMFA && !user.TOTPEnabled() {
+				http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireRole loads the user of the request and checks they hold one of roles,
+// writing the error response when they don't
+func requireRole(w http.ResponseWriter, r *http.Request, userRepo *models.UserRepository, roles []string) (*models.User, bool) {
+	userID, ok := GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if role, ok := GetRole(r); ok && !slices.Contains(roles, role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	user, err := userRepo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !slices.Contains(roles, user.Role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return user, true
+}
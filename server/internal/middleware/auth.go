@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 
@@ -15,10 +18,18 @@ type contextKey string
 const (
 	// UserIDKey is the key for user ID in the request context
 	UserIDKey contextKey = "userID"
+	// SessionIDKey is the key for the session ID in the request context
+	SessionIDKey contextKey = "sessionID"
+	// APITokenIDKey is the key for the ID of the personal access token in the request context
+	APITokenIDKey contextKey = "apiTokenID"
+	// RoleKey is the key for the role claim of the access token in the request context
+	RoleKey contextKey = "role"
 )
 
-// AuthMiddleware checks JWT Tokens and adds user info to the request context
-func AuthMiddleware(authService *auth.AuthService) func(http.Handler) http.Handler {
+// AuthMiddleware checks JWT Tokens and adds user info to the request context.
+// Personal access tokens are accepted too, but only when they hold every one of
+// scopes, so routes given no scopes are off limits to them.
+func AuthMiddleware(authService *auth.AuthService, scopes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract token from authorization header
@@ -37,6 +48,11 @@ func AuthMiddleware(authService *auth.AuthService) func(http.Handler) http.Handl
 
 			tokenString := parts[1]
 
+			if auth.IsAPIToken(tokenString) {
+				serveAPIToken(w, r, next, authService, tokenString, scopes)
+				return
+			}
+
 			// Validate the token
 			claims, err := authService.ValidateToken(tokenString)
 			if err != nil {
@@ -60,14 +76,108 @@ func AuthMiddleware(authService *auth.AuthService) func(http.Handler) http.Handl
 			// Add User ID to request context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 
+			// Tokens issued for a session also carry its ID
+			if sid, ok := claims["sid"].(string); ok {
+				if sessionID, err := uuid.Parse(sid); err == nil {
+					ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+				}
+			}
+
+			// Tokens issued before roles existed have no role claim
+			if role, ok := claims["role"].(string); ok && role != "" {
+				ctx = context.WithValue(ctx, RoleKey, role)
+			}
+
 			// Call the next handler with enhanced context
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// serveAPIToken authenticates a request made with a personal access token
+func serveAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, authService *auth.AuthService, tokenString string, scopes []string) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	token, err := authService.ValidateAPIToken(r.Context(), tokenString, ip)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
+			http.Error(w, "Invalid or Expired Token", http.StatusUnauthorized)
+		} else {
+			log.Printf("validate api token: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if len(scopes) == 0 {
+		http.Error(w, "Personal Access Tokens Not Allowed", http.StatusForbidden)
+		return
+	}
+	for _, scope := range scopes {
+		if !token.HasScope(scope) {
+			http.Error(w, "Insufficient Scope", http.StatusForbidden)
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+	ctx = context.WithValue(ctx, APITokenIDKey, token.ID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// StreamAuthMiddleware authenticates with a stream ticket passed as the "ticket"
+// query parameter, as EventSource can't set headers. Requests without a ticket
+// fall back to the Authorization header like AuthMiddleware, with the same scopes.
+func StreamAuthMiddleware(authService *auth.AuthService, scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bearer := AuthMiddleware(authService, scopes...)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := r.URL.Query().Get("ticket")
+			if ticket == "" {
+				bearer.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := authService.ValidateStreamTicket(r.Context(), ticket)
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidToken) {
+					log.Printf("stream ticket: %v", err)
+				}
+				http.Error(w, "Invalid or Expired Ticket", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // GetUserID retreives the userID from the request context
 func GetUserID(r *http.Request) (uuid.UUID, bool) {
 	userID, ok := r.Context().Value(UserIDKey).(uuid.UUID)
 	return userID, ok
 }
+
+// GetAPITokenID retrieves the ID of the personal access token the request was made with
+func GetAPITokenID(r *http.Request) (uuid.UUID, bool) {
+	tokenID, ok := r.Context().Value(APITokenIDKey).(uuid.UUID)
+	return tokenID, ok
+}
+
+// GetRole retrieves the role claim of the access token from the request context.
+// Personal access tokens and stream tickets carry no role.
+func GetRole(r *http.Request) (string, bool) {
+	role, ok := r.Context().Value(RoleKey).(string)
+	return role, ok
+}
+
+// GetSessionID retrieves the session ID of the access token from the request context
+func GetSessionID(r *http.Request) (uuid.UUID, bool) {
+	sessionID, ok := r.Context().Value(SessionIDKey).(uuid.UUID)
+	return sessionID, ok
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets the request's RemoteAddr to the client address from
+// X-Forwarded-For when the request came through one of the trusted proxies, so
+// per-client rate limits and session records see the client instead of the
+// proxy. The header is read right to left, skipping trusted proxies, so a
+// client can't pick its address by sending the header itself.
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trustedProxies) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwardedClientIP(r, trustedProxies); ok {
+				r = r.Clone(r.Context())
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP returns the first address in X-Forwarded-For, from the
+// right, that isn't a trusted proxy. It reports false unless the direct peer
+// is a trusted proxy.
+func forwardedClientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !isTrusted(peer.Addr().Unmap(), trustedProxies) {
+		return netip.Addr{}, false
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Whatever is left of a malformed hop can't be trusted
+			break
+		}
+
+		client = ip.Unmap()
+		if !isTrusted(client, trustedProxies) {
+			break
+		}
+	}
+
+	return client, client.IsValid()
+}
+
+func isTrusted(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"no trusted proxies", nil, "10.0.0.1:1234", []string{"203.0.113.7"}, "10.0.0.1:1234"},
+		{"untrusted peer", trusted, "198.51.100.1:1234", []string{"203.0.113.7"}, "198.51.100.1:1234"},
+		{"trusted peer", trusted, "10.0.0.1:1234", []string{"203.0.113.7"}, "203.0.113.7:0"},
+		{"trusted peer without header", trusted, "10.0.0.1:1234", nil, "10.0.0.1:1234"},
+		{"spoofed leftmost hop", trusted, "10.0.0.1:1234", []string{"192.0.2.1, 203.0.113.7"}, "203.0.113.7:0"},
+		{"skips trusted hops", trusted, "10.0.0.1:1234", []string{"203.0.113.7, 10.0.0.2, 10.0.0.3"}, "203.0.113.7:0"},
+		{"repeated headers", trusted, "10.0.0.1:1234", []string{"192.0.2.1", "203.0.113.7, 10.0.0.2"}, "203.0.113.7:0"},
+		{"only trusted hops", trusted, "10.0.0.1:1234", []string{"10.0.0.2, 10.0.0.3"}, "10.0.0.2:0"},
+		{"spaces around hops", trusted, "10.0.0.1:1234", []string{" 203.0.113.7 ,10.0.0.2 "}, "203.0.113.7:0"},
+		{"malformed hop", trusted, "10.0.0.1:1234", []string{"203.0.113.7, bogus"}, "10.0.0.1:1234"},
+		{"malformed hop behind client", trusted, "10.0.0.1:1234", []string{"bogus, 203.0.113.7"}, "203.0.113.7:0"},
+		{"ipv6 client", trusted, "[fd00::1]:1234", []string{"2001:db8::7"}, "[2001:db8::7]:0"},
+		{"ipv4-mapped peer", trusted, "[::ffff:10.0.0.1]:1234", []string{"203.0.113.7"}, "203.0.113.7:0"},
+		{"ipv4-mapped client", trusted, "10.0.0.1:1234", []string{"::ffff:203.0.113.7"}, "203.0.113.7:0"},
+		{"unparsable peer", trusted, "@", []string{"203.0.113.7"}, "@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+)
+
+// RequireVerified only lets users with a verified email through, it must be
+// wrapped by AuthMiddleware. Unverified users can still sign in, read and
+// manage their account, but can't add feeds or subscribe.
+func RequireVerified(userRepo *models.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := userRepo.GetUserByID(userID)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !user.EmailVerified() {
+				http.Error(w, "Email Not Verified", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
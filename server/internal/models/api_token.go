@@ -0,0 +1,208 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// APIToken is a personal access token a user created for scripts. Only a
+// SHA-256 digest of the token is stored.
+type APIToken struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+	// Prefix is the start of the token, enough to recognize it
+	Prefix     string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	LastUsedIP sql.NullString
+}
+
+// Expired reports whether the token's expiry has passed
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt.Valid && !t.ExpiresAt.Time.After(now)
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIToken returns the digest API tokens are stored and looked up by.
+// Tokens carry 256 random bits, so a plain SHA-256 can't be brute forced.
+func hashAPIToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// APITokenRepository handles database operations for personal access tokens
+type APITokenRepository struct {
+	db *sql.DB
+}
+
+// NewAPITokenRepository creates a new API token repository
+func NewAPITokenRepository(db *sql.DB) *APITokenRepository {
+	return &APITokenRepository{
+		db: db,
+	}
+}
+
+const apiTokenColumns = `id, user_id, name, token_prefix, scopes, created_at, expires_at, last_used_at, last_used_ip`
+
+func scanAPIToken(row rowScanner) (*APIToken, error) {
+	var token APIToken
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, pgtype.NewMap().SQLScanner(&token.Scopes), &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.LastUsedIP); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// CreateAPIToken stores the digest of a new token
+func (r *APITokenRepository) CreateAPIToken(ctx context.Context, userID uuid.UUID, name, token, prefix string, scopes []string, expiresAt sql.NullTime) (*APIToken, error) {
+	query :=
+		`
+		INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5::text[], $6)
+		RETURNING ` + apiTokenColumns + `;
+	`
+
+	return scanAPIToken(r.db.QueryRowContext(ctx, query, userID, name, hashAPIToken(token), prefix, scopes, expiresAt))
+}
+
+// GetAPIToken retrieves a token by its raw value, sql.ErrNoRows if there is none
+func (r *APITokenRepository) GetAPIToken(ctx context.Context, token string) (*APIToken, error) {
+	query :=
+		`
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE token_hash = $1;
+	`
+
+	return scanAPIToken(r.db.QueryRowContext(ctx, query, hashAPIToken(token)))
+}
+
+// ListAPITokens retrieves the user's tokens that haven't expired, newest first
+func (r *APITokenRepository) ListAPITokens(ctx context.Context, userID uuid.UUID) ([]*APIToken, error) {
+	query :=
+		`
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY created_at DESC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]*APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// CountAPITokens counts the user's tokens that haven't expired
+func (r *APITokenRepository) CountAPITokens(ctx context.Context, userID uuid.UUID) (int, error) {
+	query :=
+		`
+		SELECT count(*)
+		FROM api_tokens
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > now());
+	`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteAPIToken revokes a token of the user by deleting it. It returns
+// sql.ErrNoRows when the user has no such token.
+func (r *APITokenRepository) DeleteAPIToken(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2;`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteAllAPITokens revokes every token of the user, returning how many there were
+func (r *APITokenRepository) DeleteAllAPITokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id = $1;`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// TouchAPIToken records a use of the token. Uses within a minute of the last
+// recorded one are skipped, so busy scripts don't write on every request.
+func (r *APITokenRepository) TouchAPIToken(ctx context.Context, id uuid.UUID, ipAddress string) error {
+	query :=
+		`
+		UPDATE api_tokens
+		SET last_used_at = now(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, ipAddress)
+	return err
+}
+
+// DeleteExpiredAPITokens deletes up to limit tokens that expired before the
+// given time, returning how many were deleted
+func (r *APITokenRepository) DeleteExpiredAPITokens(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	query :=
+		`
+		DELETE FROM api_tokens
+		WHERE id IN (
+			SELECT id
+			FROM api_tokens
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, expiredBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
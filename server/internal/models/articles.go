@@ -3,11 +3,14 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 // Article represents an article in our system
@@ -23,6 +26,55 @@ type Article struct {
 	PublishedAt time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Seq orders articles by commit, it is only loaded by the stream queries
+	Seq int64
+}
+
+// ArticlesInsertedChannel is the Postgres NOTIFY channel signalled when new
+// articles are committed, its payload is an ArticlesInserted as JSON
+const ArticlesInsertedChannel = "articles_inserted"
+
+// ArticlesInserted tells listeners which feeds received new articles. An empty
+// FeedIDs means any feed may have, listeners should check all of them.
+type ArticlesInserted struct {
+	FeedIDs []uuid.UUID `json:"feed_ids"`
+}
+
+// ArticleStatesChangedChannel is the Postgres NOTIFY channel signalled when a
+// user marks articles read or unread, its payload is an ArticleStatesChanged as JSON
+const ArticleStatesChangedChannel = "article_states_changed"
+
+// ArticleStatesChanged tells listeners in which feeds the user's unread counts changed
+type ArticleStatesChanged struct {
+	UserID  uuid.UUID   `json:"user_id"`
+	FeedIDs []uuid.UUID `json:"feed_ids"`
+}
+
+// NOTIFY payloads are limited to 8000 bytes, beyond this many feeds the
+// notification falls back to "any feed"
+const maxNotifiedFeeds = 100
+
+// articlesInsertedPayload builds the NOTIFY payload for a batch of articles
+func articlesInsertedPayload(articles []*Article) (string, error) {
+	seen := make(map[uuid.UUID]struct{})
+	payload := ArticlesInserted{FeedIDs: make([]uuid.UUID, 0, 1)}
+	for _, a := range articles {
+		if _, ok := seen[a.FeedID]; ok {
+			continue
+		}
+		seen[a.FeedID] = struct{}{}
+		payload.FeedIDs = append(payload.FeedIDs, a.FeedID)
+	}
+	if len(payload.FeedIDs) > maxNotifiedFeeds {
+		payload.FeedIDs = nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
 }
 
 // ArticleRepository handles database operations for articles
@@ -37,27 +89,107 @@ func NewArticleRepository(db *sql.DB) *ArticleRepository {
 	}
 }
 
-// CreateArticle adds a new article to the database
-func (r *ArticleRepository) InsertManyArticlesIgnoreDuplicates(ctx context.Context, articles []*Article) error {
+// Each article binds this many parameters in a multi-row INSERT
+const articleInsertParams = 8
+
+// articleInsertChunkSize keeps every INSERT well below the 65535 bind parameter
+// limit of the Postgres protocol
+const articleInsertChunkSize = 1000
+
+// Batches larger than this are staged with COPY instead of chunked INSERTs.
+// COPY is much faster for archive-sized batches, but creating a temp table for
+// every regular fetch would bloat the system catalogs.
+const articleCopyThreshold = 5000
+
+// lockArticleSeqQuery serializes article inserts until their transaction ends.
+// Identity values are handed out when a row is inserted, not when it commits,
+// so without it a later seq could commit first and a stream reading past it
+// would never see the earlier one. Holding the lock until commit means every
+// uncommitted article has a higher seq than all committed ones.
+const lockArticleSeqQuery = `SELECT pg_advisory_xact_lock(hashtext('articles.seq'));`
+
+// prunedArticleCondition matches incoming articles v that retention already deleted
+const prunedArticleCondition = `EXISTS (
+	SELECT 1 FROM pruned_articles p
+	WHERE p.feed_id = v.feed_id AND p.guid = v.guid
+)`
+
+// InsertManyArticlesIgnoreDuplicates adds articles to the database, skipping the
+// ones already stored for the feed or pruned from it. It returns how many
+// articles were new.
+// Everything is inserted in a single transaction regardless of the batch size.
+func (r *ArticleRepository) InsertManyArticlesIgnoreDuplicates(ctx context.Context, articles []*Article) (int64, error) {
 	if len(articles) == 0 {
-		return nil
+		return 0, nil
+	}
+
+	if len(articles) > articleCopyThreshold {
+		return r.copyArticlesIgnoreDuplicates(ctx, articles)
 	}
 
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 
+	if _, err := tx.ExecContext(ctx, lockArticleSeqQuery); err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	for start := 0; start < len(articles); start += articleInsertChunkSize {
+		end := min(start+articleInsertChunkSize, len(articles))
+
+		n, err := insertArticleChunk(ctx, tx, articles[start:end])
+		if err != nil {
+			return 0, err
+		}
+		inserted += n
+	}
+
+	// Delivered to listeners once the transaction commits
+	if inserted > 0 {
+		payload, err := articlesInsertedPayload(articles)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2);`, ArticlesInsertedChannel, payload); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
+}
+
+// insertArticleChunk inserts articles with a single multi-row INSERT
+func insertArticleChunk(ctx context.Context, tx *sql.Tx, articles []*Article) (int64, error) {
+	query, args := articleInsertQuery(articles)
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// articleInsertQuery builds the multi-row INSERT of a chunk and its arguments
+func articleInsertQuery(articles []*Article) (string, []any) {
 	// Build VALUES clause dynamically
 	valueStrings := make([]string, 0, len(articles))
-	valueArgs := make([]any, 0, len(articles)*8)
+	valueArgs := make([]any, 0, len(articles)*articleInsertParams)
 
 	for i, a := range articles {
 		// ($1, $2, $3, ...)
-		offset := i * 8
+		offset := i * articleInsertParams
 		valueStrings = append(valueStrings,
-			fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
+			fmt.Sprintf("($%d::uuid,$%d,$%d,$%d,$%d,$%d,$%d,$%d::timestamp)",
 				offset+1,
 				offset+2,
 				offset+3,
@@ -92,15 +224,102 @@ func (r *ArticleRepository) InsertManyArticlesIgnoreDuplicates(ctx context.Conte
 			summary,
 			published_at
 		)
-		VALUES %s
+		SELECT v.feed_id, v.guid, v.title, v.url, v.author, v.content, v.summary, v.published_at
+		FROM (VALUES %s) AS v (feed_id, guid, title, url, author, content, summary, published_at)
+		WHERE NOT %s
 		ON CONFLICT (feed_id, guid) DO NOTHING
-	`, strings.Join(valueStrings, ","))
+	`, strings.Join(valueStrings, ","), prunedArticleCondition)
+
+	return query, valueArgs
+}
 
-	if _, err := tx.ExecContext(ctx, query, valueArgs...); err != nil {
-		return err
+// copyArticlesIgnoreDuplicates streams articles into a temp table with COPY and
+// merges them into articles in one statement. COPY isn't exposed through
+// database/sql, so it runs on the underlying pgx connection.
+func (r *ArticleRepository) copyArticlesIgnoreDuplicates(ctx context.Context, articles []*Article) (int64, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return 0, err
 	}
+	defer conn.Close()
+
+	var inserted int64
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+
+		tx, err := pgxConn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		stagingQuery :=
+			`
+			CREATE TEMP TABLE articles_staging (
+				feed_id UUID NOT NULL,
+				guid TEXT NOT NULL,
+				title TEXT NOT NULL,
+				url TEXT NOT NULL,
+				author TEXT,
+				content TEXT,
+				summary TEXT,
+				published_at TIMESTAMP
+			) ON COMMIT DROP;
+		`
+
+		if _, err := tx.Exec(ctx, stagingQuery); err != nil {
+			return err
+		}
+
+		columns := []string{"feed_id", "guid", "title", "url", "author", "content", "summary", "published_at"}
+		rows := pgx.CopyFromSlice(len(articles), func(i int) ([]any, error) {
+			a := articles[i]
+			return []any{[16]byte(a.FeedID), a.GUID, a.Title, a.URL, a.Author, a.Content, a.Summary, a.PublishedAt}, nil
+		})
 
-	return tx.Commit()
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"articles_staging"}, columns, rows); err != nil {
+			return err
+		}
+
+		// Staging ran unlocked, only the merge and commit are serialized
+		if _, err := tx.Exec(ctx, lockArticleSeqQuery); err != nil {
+			return err
+		}
+
+		// A batch may repeat a guid, only one copy of it is inserted
+		mergeQuery :=
+			`
+			INSERT INTO articles (feed_id, guid, title, url, author, content, summary, published_at)
+			SELECT DISTINCT ON (v.feed_id, v.guid) v.feed_id, v.guid, v.title, v.url, v.author, v.content, v.summary, v.published_at
+			FROM articles_staging v
+			WHERE NOT ` + prunedArticleCondition + `
+			ON CONFLICT (feed_id, guid) DO NOTHING;
+		`
+
+		tag, err := tx.Exec(ctx, mergeQuery)
+		if err != nil {
+			return err
+		}
+		inserted = tag.RowsAffected()
+
+		if inserted > 0 {
+			payload, err := articlesInsertedPayload(articles)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2);`, ArticlesInsertedChannel, payload); err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
 }
 
 func (r *ArticleRepository) GetUserSubscribedArticles(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*Article, error) {
@@ -150,3 +369,327 @@ func (r *ArticleRepository) GetUserSubscribedArticles(ctx context.Context, userI
 
 	return articles, nil
 }
+
+// keptArticleCondition matches articles that some user starred, saved or annotated
+const keptArticleCondition = `EXISTS (
+	SELECT 1 FROM article_states s
+	WHERE s.article_id = a.id AND (s.starred OR s.saved OR s.note <> '')
+)`
+
+// DeleteExpiredArticles deletes up to limit articles stored longer than their
+// feed's retention_days, or defaultDays for feeds without an override. A
+// retention of 0 days keeps articles forever. Kept articles are never deleted
+// and rows locked by other transactions are skipped. Deleted guids are recorded
+// in pruned_articles so they aren't fetched again. It returns how many articles
+// were deleted.
+func (r *ArticleRepository) DeleteExpiredArticles(ctx context.Context, defaultDays, limit int) (int64, error) {
+	query :=
+		`
+		WITH deleted AS (
+			DELETE FROM articles
+			WHERE id IN (
+				SELECT a.id
+				FROM articles a
+				JOIN feeds f ON f.id = a.feed_id
+				WHERE COALESCE(f.retention_days, $1) > 0
+				AND a.created_at < now() - make_interval(days => COALESCE(f.retention_days, $1))
+				AND NOT ` + keptArticleCondition + `
+				LIMIT $2
+				FOR UPDATE OF a SKIP LOCKED
+			)
+			RETURNING feed_id, guid
+		), recorded AS (
+			INSERT INTO pruned_articles (feed_id, guid)
+			SELECT feed_id, guid FROM deleted
+			ON CONFLICT (feed_id, guid) DO NOTHING
+		)
+		SELECT count(*) FROM deleted;
+	`
+
+	var deleted int64
+	if err := r.db.QueryRowContext(ctx, query, defaultDays, limit).Scan(&deleted); err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// ArticleLimit is the maximum number of articles kept for a feed
+type ArticleLimit struct {
+	FeedID      uuid.UUID
+	MaxArticles int
+}
+
+// GetFeedsOverArticleLimit lists the feeds storing more articles than their
+// retention_max_articles, or defaultMax for feeds without an override. A
+// maximum of 0 keeps every article.
+func (r *ArticleRepository) GetFeedsOverArticleLimit(ctx context.Context, defaultMax int) ([]ArticleLimit, error) {
+	query :=
+		`
+		SELECT f.id, COALESCE(f.retention_max_articles, $1)
+		FROM feeds f
+		JOIN articles a ON a.feed_id = f.id
+		WHERE COALESCE(f.retention_max_articles, $1) > 0
+		GROUP BY f.id
+		HAVING count(*) > COALESCE(f.retention_max_articles, $1);
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, defaultMax)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make([]ArticleLimit, 0)
+	for rows.Next() {
+		var limit ArticleLimit
+		if err := rows.Scan(&limit.FeedID, &limit.MaxArticles); err != nil {
+			return nil, err
+		}
+		limits = append(limits, limit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// DeleteFeedArticlesOverLimit deletes up to limit articles of a feed beyond its
+// newest maxArticles. Only that feed's articles are ranked. Kept articles count
+// towards the maximum but are never deleted. Deleted guids are recorded in
+// pruned_articles so they aren't fetched again. It returns how many articles
+// were deleted.
+func (r *ArticleRepository) DeleteFeedArticlesOverLimit(ctx context.Context, feedID uuid.UUID, maxArticles, limit int) (int64, error) {
+	query :=
+		`
+		WITH deleted AS (
+			DELETE FROM articles
+			WHERE id IN (
+				SELECT a.id
+				FROM (
+					SELECT id
+					FROM articles
+					WHERE feed_id = $1
+					ORDER BY published_at DESC, created_at DESC
+					OFFSET $2
+				) ranked
+				JOIN articles a ON a.id = ranked.id
+				WHERE NOT ` + keptArticleCondition + `
+				LIMIT $3
+				FOR UPDATE OF a SKIP LOCKED
+			)
+			RETURNING feed_id, guid
+		), recorded AS (
+			INSERT INTO pruned_articles (feed_id, guid)
+			SELECT feed_id, guid FROM deleted
+			ON CONFLICT (feed_id, guid) DO NOTHING
+		)
+		SELECT count(*) FROM deleted;
+	`
+
+	var deleted int64
+	if err := r.db.QueryRowContext(ctx, query, feedID, maxArticles, limit).Scan(&deleted); err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// GetUserArticlesAfter retrieves up to limit articles of the user's subscribed
+// feeds that were stored after the article at afterSeq, oldest first
+func (r *ArticleRepository) GetUserArticlesAfter(ctx context.Context, userID uuid.UUID, afterSeq int64, limit int) ([]*Article, error) {
+	query :=
+		`
+		SELECT a.id, a.feed_id, a.guid, a.title, a.url, a.author, a.content, a.summary, a.published_at, a.created_at, a.updated_at, a.seq
+		FROM articles a
+		JOIN feed_subscriptions fs ON a.feed_id = fs.feed_id
+		WHERE fs.user_id = $1 AND a.seq > $2
+		ORDER BY a.seq ASC
+		LIMIT $3;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := make([]*Article, 0)
+	for rows.Next() {
+		var article Article
+
+		if err := rows.Scan(
+			&article.ID,
+			&article.FeedID,
+			&article.GUID,
+			&article.Title,
+			&article.URL,
+			&article.Author,
+			&article.Content,
+			&article.Summary,
+			&article.PublishedAt,
+			&article.CreatedAt,
+			&article.UpdatedAt,
+			&article.Seq,
+		); err != nil {
+			return nil, err
+		}
+
+		articles = append(articles, &article)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetLatestArticleSeq returns the seq of the newest article, 0 if there are none
+func (r *ArticleRepository) GetLatestArticleSeq(ctx context.Context) (int64, error) {
+	query :=
+		`
+		SELECT COALESCE(max(seq), 0)
+		FROM articles;
+	`
+
+	var seq int64
+	if err := r.db.QueryRowContext(ctx, query).Scan(&seq); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// GetUnreadCounts counts the articles the user hasn't read in each of the given feeds
+func (r *ArticleRepository) GetUnreadCounts(ctx context.Context, userID uuid.UUID, feedIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	query :=
+		`
+		SELECT a.feed_id, count(*)
+		FROM articles a
+		LEFT JOIN article_states s ON s.article_id = a.id AND s.user_id = $1
+		WHERE a.feed_id = ANY($2::uuid[])
+		AND (s.read IS NULL OR NOT s.read)
+		GROUP BY a.feed_id;
+	`
+
+	ids := make([]string, 0, len(feedIDs))
+	for _, id := range feedIDs {
+		ids = append(ids, id.String())
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Feeds without unread articles have no row, report them as 0
+	counts := make(map[uuid.UUID]int64, len(feedIDs))
+	for _, id := range feedIDs {
+		counts[id] = 0
+	}
+
+	for rows.Next() {
+		var feedID uuid.UUID
+		var count int64
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// ArticleState is a user's read, starred, saved and note state of an article
+type ArticleState struct {
+	UserID    uuid.UUID
+	ArticleID uuid.UUID
+	Read      bool
+	Starred   bool
+	Saved     bool
+	Note      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ArticleStateUpdate holds the state fields to change, invalid fields keep
+// their current value
+type ArticleStateUpdate struct {
+	Read    sql.NullBool
+	Starred sql.NullBool
+	Saved   sql.NullBool
+	Note    sql.NullString
+}
+
+// UpsertArticleState applies update to the user's state of an article, creating
+// it if needed. Only articles of feeds the user subscribes to can be updated,
+// sql.ErrNoRows is returned for any other article. Changing the read state
+// notifies ArticleStatesChangedChannel on commit.
+func (r *ArticleRepository) UpsertArticleState(ctx context.Context, userID, articleID uuid.UUID, update ArticleStateUpdate) (*ArticleState, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+		INSERT INTO article_states (user_id, article_id, read, starred, saved, note)
+		SELECT fs.user_id, a.id, COALESCE($3, FALSE), COALESCE($4, FALSE), COALESCE($5, FALSE), COALESCE($6, '')
+		FROM articles a
+		JOIN feed_subscriptions fs ON fs.feed_id = a.feed_id AND fs.user_id = $1
+		WHERE a.id = $2
+		ON CONFLICT (user_id, article_id) DO UPDATE
+		SET read = COALESCE($3, article_states.read),
+		    starred = COALESCE($4, article_states.starred),
+		    saved = COALESCE($5, article_states.saved),
+		    note = COALESCE($6, article_states.note),
+		    updated_at = now()
+		RETURNING user_id, article_id, read, starred, saved, note, created_at, updated_at;
+	`
+
+	var state ArticleState
+	err = tx.QueryRowContext(ctx, query, userID, articleID, update.Read, update.Starred, update.Saved, update.Note).Scan(
+		&state.UserID,
+		&state.ArticleID,
+		&state.Read,
+		&state.Starred,
+		&state.Saved,
+		&state.Note,
+		&state.CreatedAt,
+		&state.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Read.Valid {
+		payload := ArticleStatesChanged{UserID: userID, FeedIDs: make([]uuid.UUID, 1)}
+		if err := tx.QueryRowContext(ctx, `SELECT feed_id FROM articles WHERE id = $1;`, articleID).Scan(&payload.FeedIDs[0]); err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2);`, ArticleStatesChangedChannel, string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestArticlesInsertedPayload(t *testing.T) {
+	feedA, feedB := uuid.New(), uuid.New()
+
+	many := make([]*Article, 0, maxNotifiedFeeds+1)
+	for range maxNotifiedFeeds + 1 {
+		many = append(many, &Article{FeedID: uuid.New()})
+	}
+
+	tests := []struct {
+		name     string
+		articles []*Article
+		want     []uuid.UUID
+	}{
+		{"single feed", []*Article{{FeedID: feedA}, {FeedID: feedA}}, []uuid.UUID{feedA}},
+		{"keeps first seen order", []*Article{{FeedID: feedB}, {FeedID: feedA}, {FeedID: feedB}}, []uuid.UUID{feedB, feedA}},
+		{"at the limit", many[:maxNotifiedFeeds], feedIDsOf(many[:maxNotifiedFeeds])},
+		{"over the limit means any feed", many, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := articlesInsertedPayload(tt.articles)
+			if err != nil {
+				t.Fatalf("articlesInsertedPayload error: %v", err)
+			}
+			// NOTIFY rejects payloads of 8000 bytes or more
+			if len(payload) >= 8000 {
+				t.Errorf("payload is %d bytes, too long for NOTIFY", len(payload))
+			}
+
+			var got ArticlesInserted
+			if err := json.Unmarshal([]byte(payload), &got); err != nil {
+				t.Fatalf("payload %q isn't JSON: %v", payload, err)
+			}
+			if !reflect.DeepEqual(got.FeedIDs, tt.want) {
+				t.Errorf("FeedIDs = %v, want %v", got.FeedIDs, tt.want)
+			}
+		})
+	}
+}
+
+func feedIDsOf(articles []*Article) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(articles))
+	for _, a := range articles {
+		ids = append(ids, a.FeedID)
+	}
+	return ids
+}
+
+func TestArticleInsertQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{"single article", 1},
+		{"two articles", 2},
+		{"full chunk", articleInsertChunkSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles := make([]*Article, 0, tt.count)
+			for i := range tt.count {
+				articles = append(articles, &Article{
+					FeedID:      uuid.New(),
+					GUID:        fmt.Sprintf("guid-%d", i),
+					Title:       "Title",
+					URL:         "https://example.com/post",
+					Author:      "Author",
+					PublishedAt: time.Now(),
+				})
+			}
+
+			query, args := articleInsertQuery(articles)
+
+			if len(args) != tt.count*articleInsertParams {
+				t.Fatalf("got %d arguments, want %d", len(args), tt.count*articleInsertParams)
+			}
+			// The Postgres protocol can't bind more parameters
+			if len(args) > 65535 {
+				t.Errorf("got %d arguments, over the bind parameter limit", len(args))
+			}
+
+			last := tt.count * articleInsertParams
+			if !strings.Contains(query, fmt.Sprintf("$%d::timestamp)", last)) {
+				t.Errorf("query doesn't bind the last parameter $%d", last)
+			}
+			if strings.Contains(query, fmt.Sprintf("$%d", last+1)) {
+				t.Errorf("query binds $%d beyond the arguments", last+1)
+			}
+			if got := strings.Count(query, "::uuid,"); got != tt.count {
+				t.Errorf("query has %d rows, want %d", got, tt.count)
+			}
+
+			first := articles[0]
+			want := []any{first.FeedID, first.GUID, first.Title, first.URL, first.Author, first.Content, first.Summary, first.PublishedAt}
+			if !reflect.DeepEqual(args[:articleInsertParams], want) {
+				t.Errorf("first row arguments = %v, want %v", args[:articleInsertParams], want)
+			}
+		})
+	}
+}
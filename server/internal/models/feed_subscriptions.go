@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,10 +14,17 @@ type FeedSubscription struct {
 	UserID      uuid.UUID
 	FeedID      uuid.UUID
 	CustomTitle string
+	Folder      string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
+// SubscribedFeed is a subscription together with the feed it points to
+type SubscribedFeed struct {
+	Subscription FeedSubscription
+	Feed         Feed
+}
+
 // FeedSubscriptionRepository handles database operations for feed_subscriptions
 type FeedSubscriptionRepository struct {
 	db *sql.DB
@@ -27,28 +35,72 @@ func NewFeedSubscriptionRepository(db *sql.DB) *FeedSubscriptionRepository {
 	return &FeedSubscriptionRepository{db: db}
 }
 
-// SubscribeUserToFeed creates a new Feed Subscription
-func (r *FeedSubscriptionRepository) SubscribeUserToFeed(userID, feedID uuid.UUID, customTitle string) (*FeedSubscription, error) {
+// SubscribeUserToFeed creates a new Feed Subscription. Folder is a "/" separated
+// path, an empty folder places the subscription at the top level.
+func (r *FeedSubscriptionRepository) SubscribeUserToFeed(userID, feedID uuid.UUID, customTitle, folder string) (*FeedSubscription, error) {
 	feedSubscription := &FeedSubscription{
 		UserID:      userID,
 		FeedID:      feedID,
 		CustomTitle: customTitle,
+		Folder:      folder,
 	}
 
 	query :=
 		`
-		INSERT INTO feed_subscriptions (user_id, feed_id, custom_title)
-		VALUES ($1, $2, $3)
+		INSERT INTO feed_subscriptions (user_id, feed_id, custom_title, folder)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at;
 	`
 
-	if err := r.db.QueryRow(query, userID, feedID, customTitle).Scan(&feedSubscription.ID, &feedSubscription.CreatedAt, &feedSubscription.UpdatedAt); err != nil {
+	if err := r.db.QueryRow(query, userID, feedID, customTitle, folder).Scan(&feedSubscription.ID, &feedSubscription.CreatedAt, &feedSubscription.UpdatedAt); err != nil {
 		return nil, err
 	}
 
 	return feedSubscription, nil
 }
 
+// EnsureSubscription subscribes the user to the feed unless they already are.
+// created reports whether a new subscription was inserted.
+func (r *FeedSubscriptionRepository) EnsureSubscription(userID, feedID uuid.UUID, customTitle, folder string) (feedSubscription *FeedSubscription, created bool, err error) {
+	feedSubscription = &FeedSubscription{
+		UserID:      userID,
+		FeedID:      feedID,
+		CustomTitle: customTitle,
+		Folder:      folder,
+	}
+
+	query :=
+		`
+		INSERT INTO feed_subscriptions (user_id, feed_id, custom_title, folder)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, feed_id) DO NOTHING
+		RETURNING id, created_at, updated_at;
+	`
+
+	err = r.db.QueryRow(query, userID, feedID, customTitle, folder).Scan(&feedSubscription.ID, &feedSubscription.CreatedAt, &feedSubscription.UpdatedAt)
+	if err == nil {
+		return feedSubscription, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// The subscription already exists, return it as stored
+	existingQuery :=
+		`
+		SELECT id, user_id, feed_id, COALESCE(custom_title, ''), folder, created_at, updated_at
+		FROM feed_subscriptions
+		WHERE user_id = $1 AND feed_id = $2;
+	`
+
+	existing := &FeedSubscription{}
+	if err := r.db.QueryRow(existingQuery, userID, feedID).Scan(&existing.ID, &existing.UserID, &existing.FeedID, &existing.CustomTitle, &existing.Folder, &existing.CreatedAt, &existing.UpdatedAt); err != nil {
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
 func (r *FeedSubscriptionRepository) DeleteSubscription(userID, feedID uuid.UUID) error {
 	query :=
 		`
@@ -75,7 +127,7 @@ func (r *FeedSubscriptionRepository) DeleteSubscription(userID, feedID uuid.UUID
 func (r *FeedSubscriptionRepository) GetSubscriptionsByUser(userID uuid.UUID) ([]*FeedSubscription, error) {
 	query :=
 		`
-		SELECT id, user_id, feed_id, custom_title, created_at, updated_at
+		SELECT id, user_id, feed_id, custom_title, folder, created_at, updated_at
 		FROM feed_subscriptions
 		WHERE user_id = $1
 		ORDER BY created_at DESC;
@@ -92,7 +144,7 @@ func (r *FeedSubscriptionRepository) GetSubscriptionsByUser(userID uuid.UUID) ([
 
 	for rows.Next() {
 		var feedSubscription FeedSubscription
-		if err := rows.Scan(&feedSubscription.ID, &feedSubscription.UserID, &feedSubscription.FeedID, &feedSubscription.CustomTitle, &feedSubscription.CreatedAt, &feedSubscription.UpdatedAt); err != nil {
+		if err := rows.Scan(&feedSubscription.ID, &feedSubscription.UserID, &feedSubscription.FeedID, &feedSubscription.CustomTitle, &feedSubscription.Folder, &feedSubscription.CreatedAt, &feedSubscription.UpdatedAt); err != nil {
 			return nil, err
 		}
 
@@ -107,6 +159,46 @@ func (r *FeedSubscriptionRepository) GetSubscriptionsByUser(userID uuid.UUID) ([
 	return feedSubscriptions, nil
 }
 
+// GetSubscribedFeedsByUser returns the user's subscriptions joined with their feeds, ordered by folder and title
+func (r *FeedSubscriptionRepository) GetSubscribedFeedsByUser(userID uuid.UUID) ([]*SubscribedFeed, error) {
+	query :=
+		`
+		SELECT fs.id, fs.user_id, fs.feed_id, COALESCE(fs.custom_title, ''), fs.folder, fs.created_at, fs.updated_at,
+		       f.id, f.feed_url, COALESCE(f.site_url, ''), COALESCE(f.title, ''), COALESCE(f.description, ''), f.created_at, f.updated_at, f.last_fetched_at
+		FROM feed_subscriptions fs
+		JOIN feeds f ON f.id = fs.feed_id
+		WHERE fs.user_id = $1
+		ORDER BY fs.folder, lower(COALESCE(NULLIF(fs.custom_title, ''), f.title));
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscribedFeeds := make([]*SubscribedFeed, 0)
+
+	for rows.Next() {
+		var sf SubscribedFeed
+		sub, feed := &sf.Subscription, &sf.Feed
+		if err := rows.Scan(
+			&sub.ID, &sub.UserID, &sub.FeedID, &sub.CustomTitle, &sub.Folder, &sub.CreatedAt, &sub.UpdatedAt,
+			&feed.ID, &feed.FeedURL, &feed.SiteURL, &feed.Title, &feed.Description, &feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetchedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		subscribedFeeds = append(subscribedFeeds, &sf)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscribedFeeds, nil
+}
+
 func (r *FeedSubscriptionRepository) Exists(userID, feedID uuid.UUID) (bool, error) {
 	query := `
 		SELECT EXISTS (
@@ -3,11 +3,16 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrFeedExists = errors.New("feed already exists")
+)
+
 // Feed represents a feed in our system
 type Feed struct {
 	ID            uuid.UUID
@@ -32,8 +37,9 @@ func NewFeedRepository(db *sql.DB) *FeedRepository {
 	}
 }
 
-// CreateFeed adds a new feed to the database
-func (r *FeedRepository) CreateFeed(feedURL, siteURL, title, description string) (*Feed, error) {
+// CreateFeed adds a new feed to the database. It returns ErrFeedExists when
+// another feed already has the URL or its key.
+func (r *FeedRepository) CreateFeed(feedURL, urlKey, siteURL, title, description string) (*Feed, error) {
 	feed := &Feed{
 		FeedURL:     feedURL,
 		SiteURL:     siteURL,
@@ -43,18 +49,52 @@ func (r *FeedRepository) CreateFeed(feedURL, siteURL, title, description string)
 
 	query :=
 		`
-		INSERT INTO feeds (feed_url, site_url, title, description)
-		VALUES ($1, $2, $3, $4) 
+		INSERT INTO feeds (feed_url, url_key, site_url, title, description)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at, last_fetched_at;
 	`
 
-	if err := r.db.QueryRow(query, feed.FeedURL, feed.SiteURL, feed.Title, feed.Description).Scan(&feed.ID, &feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetchedAt); err != nil {
+	if err := r.db.QueryRow(query, feed.FeedURL, urlKey, feed.SiteURL, feed.Title, feed.Description).Scan(&feed.ID, &feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetchedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrFeedExists
+		}
 		return nil, err
 	}
 
 	return feed, nil
 }
 
+// FindOrCreateFeed returns the feed stored under feedURL or urlKey, inserting it
+// first if it does not exist yet. Conflicting inserts are ignored so concurrent
+// callers converge on the same row instead of failing on a unique constraint.
+// created reports whether this call inserted the row.
+func (r *FeedRepository) FindOrCreateFeed(feedURL, urlKey, siteURL, title, description string) (feed *Feed, created bool, err error) {
+	query :=
+		`
+		INSERT INTO feeds (feed_url, url_key, site_url, title, description)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING id, feed_url, site_url, title, description, created_at, updated_at, last_fetched_at;
+	`
+
+	feed = &Feed{}
+	err = r.db.QueryRow(query, feedURL, urlKey, siteURL, title, description).Scan(&feed.ID, &feed.FeedURL, &feed.SiteURL, &feed.Title, &feed.Description, &feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetchedAt)
+	if err == nil {
+		return feed, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// Someone else inserted the feed first, return their row
+	feed, err = r.GetFeedByURL(feedURL, urlKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return feed, false, nil
+}
+
 // GetAllFeeds retrieves all feeds from the database
 func (r *FeedRepository) GetAllFeeds() ([]*Feed, error) {
 	query :=
@@ -124,38 +164,52 @@ func (r *FeedRepository) UpdateLastFetchedAt(id uuid.UUID) error {
 	return nil
 }
 
-// GetFeedByURL gets a feed by its unique URL
-func (r *FeedRepository) GetFeedByURL(feedURL string) (*Feed, error) {
+// GetFeedByURL gets a feed by its normalized URL or duplicate detection key.
+// Rows from before url_key existed are still matched on the exact URL.
+func (r *FeedRepository) GetFeedByURL(feedURL, urlKey string) (*Feed, error) {
 	query :=
 		`
 	SELECT id, feed_url, site_url, title, description, created_at, updated_at, last_fetched_at
 	FROM feeds
-	WHERE feed_url = $1;
+	WHERE url_key = $2 OR feed_url = $1
+	ORDER BY url_key = $2 DESC NULLS LAST
+	LIMIT 1;
 	`
 
 	var feed Feed
-	if err := r.db.QueryRow(query, feedURL).Scan(&feed.ID, &feed.FeedURL, &feed.SiteURL, feed.Title, feed.Description, feed.CreatedAt, feed.UpdatedAt, feed.LastFetchedAt); err != nil {
+	if err := r.db.QueryRow(query, feedURL, urlKey).Scan(&feed.ID, &feed.FeedURL, &feed.SiteURL, &feed.Title, &feed.Description, &feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetchedAt); err != nil {
 		return nil, err
 	}
 
 	return &feed, nil
 }
 
-// GetNextFeedsToFetch retrieves feeds that haven't been fetched in the last duration
-func (r *FeedRepository) GetNextFeedsToFetch(ctx context.Context, limit int, olderThan time.Duration) ([]*Feed, error) {
-	cutoff := time.Now().Add(-olderThan)
-
+// ClaimFeedsToFetch atomically leases up to limit feeds that haven't been fetched
+// in the last olderThan to the given owner. Rows locked or leased by other
+// workers are skipped, so any number of instances can claim concurrently without
+// fetching the same feed twice. A lease that isn't released within leaseTTL
+// expires and the feed becomes claimable again.
+// Feeds nobody subscribes to are never claimed, they are picked up again as soon
+// as someone subscribes.
+func (r *FeedRepository) ClaimFeedsToFetch(ctx context.Context, owner string, limit int, olderThan, leaseTTL time.Duration) ([]*Feed, error) {
 	query :=
 		`
-		SELECT id, feed_url, site_url, title, description, created_at, updated_at, last_fetched_at
-		FROM feeds
-		WHERE last_fetched_at IS NULL 
-		OR last_fetched_at < $2
-		ORDER BY last_fetched_at ASC NULLS FIRST
-		LIMIT $1;
+		UPDATE feeds
+		SET leased_by = $1, lease_expires_at = now() + make_interval(secs => $4)
+		WHERE id IN (
+			SELECT id
+			FROM feeds
+			WHERE (last_fetched_at IS NULL OR last_fetched_at < now() - make_interval(secs => $3))
+			AND (lease_expires_at IS NULL OR lease_expires_at < now())
+			AND EXISTS (SELECT 1 FROM feed_subscriptions fs WHERE fs.feed_id = feeds.id)
+			ORDER BY last_fetched_at ASC NULLS FIRST
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, feed_url, site_url, title, description, created_at, updated_at, last_fetched_at;
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, cutoff)
+	rows, err := r.db.QueryContext(ctx, query, owner, limit, olderThan.Seconds(), leaseTTL.Seconds())
 	if err != nil {
 		return nil, err
 	}
@@ -171,5 +225,406 @@ func (r *FeedRepository) GetNextFeedsToFetch(ctx context.Context, limit int, old
 		feeds = append(feeds, &feed)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return feeds, nil
 }
+
+// LeaseFeed leases a single feed to owner unless another owner holds an unexpired lease.
+// It reports whether the lease was acquired; owners may renew their own leases.
+func (r *FeedRepository) LeaseFeed(ctx context.Context, id uuid.UUID, owner string, leaseTTL time.Duration) (bool, error) {
+	query :=
+		`
+		UPDATE feeds
+		SET leased_by = $2, lease_expires_at = now() + make_interval(secs => $3)
+		WHERE id = $1
+		AND (lease_expires_at IS NULL OR lease_expires_at < now() OR leased_by = $2);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, owner, leaseTTL.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// ReleaseFeedLease marks the feed as fetched and gives up the owner's lease on it
+func (r *FeedRepository) ReleaseFeedLease(ctx context.Context, id uuid.UUID, owner string) error {
+	query :=
+		`
+		UPDATE feeds
+		SET last_fetched_at = now(), updated_at = now(), leased_by = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND leased_by = $2;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, owner)
+	return err
+}
+
+// FeedURLInfo is the subset of a feed needed to find duplicate feeds
+type FeedURLInfo struct {
+	ID          uuid.UUID
+	FeedURL     string
+	URLKey      sql.NullString
+	CreatedAt   time.Time
+	Subscribers int
+}
+
+// ListFeedURLs retrieves the URL and subscriber count of every feed
+func (r *FeedRepository) ListFeedURLs(ctx context.Context) ([]*FeedURLInfo, error) {
+	query :=
+		`
+		SELECT f.id, f.feed_url, f.url_key, f.created_at, COUNT(fs.id)
+		FROM feeds f
+		LEFT JOIN feed_subscriptions fs ON fs.feed_id = f.id
+		GROUP BY f.id
+		ORDER BY f.created_at ASC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := make([]*FeedURLInfo, 0)
+	for rows.Next() {
+		var info FeedURLInfo
+		if err := rows.Scan(&info.ID, &info.FeedURL, &info.URLKey, &info.CreatedAt, &info.Subscribers); err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, &info)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// CountFeedsWithoutURLKey counts the feeds from before url_key existed
+func (r *FeedRepository) CountFeedsWithoutURLKey(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM feeds WHERE url_key IS NULL;`).Scan(&count)
+	return count, err
+}
+
+// LockFeedMerge takes a session lock shared by every instance, so only one of
+// them merges duplicate feeds at a time. Call unlock once done.
+func (r *FeedRepository) LockFeedMerge(ctx context.Context) (unlock func(), err error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext('feeds.merge'));`); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('feeds.merge'));`)
+		conn.Close()
+	}, nil
+}
+
+// UpdateFeedURL stores the normalized URL and duplicate detection key of a feed
+func (r *FeedRepository) UpdateFeedURL(ctx context.Context, id uuid.UUID, feedURL, urlKey string) error {
+	query :=
+		`
+		UPDATE feeds
+		SET feed_url = $2, url_key = $3, updated_at = now()
+		WHERE id = $1;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, feedURL, urlKey)
+	return err
+}
+
+// MergeFeeds folds the duplicate feeds into keepID in a single transaction.
+// Subscriptions and articles are moved over unless the kept feed already has
+// them, whatever is left is removed together with the duplicate feed rows.
+// Finally the kept feed is stored under the normalized URL and key.
+func (r *FeedRepository) MergeFeeds(ctx context.Context, keepID uuid.UUID, duplicateIDs []uuid.UUID, feedURL, urlKey string) (movedSubscriptions, movedArticles int64, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock every row of the group so the fetcher can't touch them mid-merge
+	lockQuery :=
+		`
+		SELECT id FROM feeds WHERE id = $1 OR id = ANY($2::uuid[]) FOR UPDATE;
+	`
+
+	ids := make([]string, 0, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		ids = append(ids, id.String())
+	}
+
+	if _, err := tx.ExecContext(ctx, lockQuery, keepID, ids); err != nil {
+		return 0, 0, err
+	}
+
+	moveSubscriptionsQuery :=
+		`
+		UPDATE feed_subscriptions fs
+		SET feed_id = $1, updated_at = now()
+		WHERE fs.feed_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM feed_subscriptions k
+			WHERE k.user_id = fs.user_id AND k.feed_id = $1
+		);
+	`
+
+	moveArticlesQuery :=
+		`
+		UPDATE articles a
+		SET feed_id = $1, updated_at = now()
+		WHERE a.feed_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM articles k
+			WHERE k.feed_id = $1 AND k.guid = a.guid
+		);
+	`
+
+	// One duplicate at a time, so a user subscribed to several duplicates
+	// only ends up with a single subscription
+	for _, duplicateID := range duplicateIDs {
+		res, err := tx.ExecContext(ctx, moveSubscriptionsQuery, keepID, duplicateID)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, 0, err
+		}
+		movedSubscriptions += n
+
+		res, err = tx.ExecContext(ctx, moveArticlesQuery, keepID, duplicateID)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err = res.RowsAffected()
+		if err != nil {
+			return 0, 0, err
+		}
+		movedArticles += n
+	}
+
+	// Remaining subscriptions and articles are removed by ON DELETE CASCADE
+	if _, err := tx.ExecContext(ctx, `DELETE FROM feeds WHERE id = ANY($1::uuid[]);`, ids); err != nil {
+		return 0, 0, err
+	}
+
+	updateQuery :=
+		`
+		UPDATE feeds
+		SET feed_url = $2, url_key = $3, updated_at = now()
+		WHERE id = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, updateQuery, keepID, feedURL, urlKey); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	return movedSubscriptions, movedArticles, nil
+}
+
+// FeedRetention holds a feed's overrides of the global retention policy, NULL
+// means the global default applies
+type FeedRetention struct {
+	FeedID      uuid.UUID
+	Days        sql.NullInt32
+	MaxArticles sql.NullInt32
+}
+
+// GetFeedRetention retrieves the retention overrides of a feed
+func (r *FeedRepository) GetFeedRetention(ctx context.Context, id uuid.UUID) (*FeedRetention, error) {
+	query :=
+		`
+		SELECT id, retention_days, retention_max_articles
+		FROM feeds
+		WHERE id = $1;
+	`
+
+	var retention FeedRetention
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&retention.FeedID, &retention.Days, &retention.MaxArticles); err != nil {
+		return nil, err
+	}
+
+	return &retention, nil
+}
+
+// UpdateFeedRetention sets the retention overrides of a feed
+func (r *FeedRepository) UpdateFeedRetention(ctx context.Context, retention *FeedRetention) error {
+	query :=
+		`
+		UPDATE feeds
+		SET retention_days = $2, retention_max_articles = $3, updated_at = now()
+		WHERE id = $1;
+	`
+
+	res, err := r.db.ExecContext(ctx, query, retention.FeedID, retention.Days, retention.MaxArticles)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MarkOrphanedFeeds stamps orphaned_at on feeds that lost their last subscriber
+// and clears it on feeds that gained one again. It returns how many feeds were
+// newly orphaned and how many were revived.
+func (r *FeedRepository) MarkOrphanedFeeds(ctx context.Context) (orphaned, revived int64, err error) {
+	orphanQuery :=
+		`
+		UPDATE feeds f
+		SET orphaned_at = now()
+		WHERE f.orphaned_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM feed_subscriptions fs WHERE fs.feed_id = f.id);
+	`
+
+	reviveQuery :=
+		`
+		UPDATE feeds f
+		SET orphaned_at = NULL
+		WHERE f.orphaned_at IS NOT NULL
+		AND EXISTS (SELECT 1 FROM feed_subscriptions fs WHERE fs.feed_id = f.id);
+	`
+
+	res, err := r.db.ExecContext(ctx, orphanQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	if orphaned, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = r.db.ExecContext(ctx, reviveQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	if revived, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	return orphaned, revived, nil
+}
+
+// DeleteOrphanedFeeds deletes up to limit feeds that have had no subscribers
+// since before orphanedBefore, their articles are removed by ON DELETE CASCADE.
+// Feeds with articles a user starred, saved or annotated are kept. Feeds locked
+// by a concurrent subscribe are skipped, so a feed is never deleted from under
+// a new subscription. It returns how many feeds were deleted.
+func (r *FeedRepository) DeleteOrphanedFeeds(ctx context.Context, orphanedBefore time.Time, limit int) (int64, error) {
+	query :=
+		`
+		DELETE FROM feeds
+		WHERE id IN (
+			SELECT f.id
+			FROM feeds f
+			WHERE f.orphaned_at < $1
+			AND NOT EXISTS (SELECT 1 FROM feed_subscriptions fs WHERE fs.feed_id = f.id)
+			AND NOT EXISTS (
+				SELECT 1 FROM articles a
+				JOIN article_states s ON s.article_id = a.id
+				WHERE a.feed_id = f.id AND (s.starred OR s.saved OR s.note <> '')
+			)
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, orphanedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// DeleteFeed deletes a feed along with its subscriptions and articles, it returns
+// sql.ErrNoRows when there is no such feed
+func (r *FeedRepository) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM feeds WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// FeedStats summarizes feeds and subscriptions for monitoring
+type FeedStats struct {
+	Feeds           int64
+	SubscribedFeeds int64
+	Subscriptions   int64
+	// OverdueFeeds are subscribed feeds not fetched within the fetch interval
+	OverdueFeeds int64
+	// Lag is how long the oldest overdue feed has been due
+	Lag time.Duration
+}
+
+// GetFeedStats counts feeds and subscriptions and measures how far the fetch
+// scheduler is behind. Feeds that were never fetched are due since they were created.
+func (r *FeedRepository) GetFeedStats(ctx context.Context, fetchInterval time.Duration) (*FeedStats, error) {
+	query :=
+		`
+		WITH subscribed AS (
+			SELECT f.id, COALESCE(f.last_fetched_at, f.created_at) AS fetched_at, f.last_fetched_at IS NULL AS never_fetched
+			FROM feeds f
+			WHERE EXISTS (SELECT 1 FROM feed_subscriptions fs WHERE fs.feed_id = f.id)
+		), overdue AS (
+			SELECT CASE WHEN never_fetched THEN fetched_at ELSE fetched_at + make_interval(secs => $1) END AS due_at
+			FROM subscribed
+			WHERE never_fetched OR fetched_at < now() - make_interval(secs => $1)
+		)
+		SELECT
+			(SELECT count(*) FROM feeds),
+			(SELECT count(*) FROM subscribed),
+			(SELECT count(*) FROM feed_subscriptions),
+			(SELECT count(*) FROM overdue),
+			COALESCE((SELECT EXTRACT(EPOCH FROM now() - min(due_at)) FROM overdue), 0)::float8;
+	`
+
+	var stats FeedStats
+	var lagSeconds float64
+	if err := r.db.QueryRowContext(ctx, query, fetchInterval.Seconds()).Scan(&stats.Feeds, &stats.SubscribedFeeds, &stats.Subscriptions, &stats.OverdueFeeds, &lagSeconds); err != nil {
+		return nil, err
+	}
+	stats.Lag = time.Duration(lagSeconds * float64(time.Second))
+
+	return &stats, nil
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrIdentityLinked = errors.New("identity already linked")
+)
+
+// UserIdentity is an account at an external OpenID Connect provider linked to a user
+type UserIdentity struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// Issuer and Subject identify the account at the provider
+	Issuer      string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt sql.NullTime
+}
+
+// IdentityRepository handles database operations for external identities
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{
+		db: db,
+	}
+}
+
+const identityColumns = `id, user_id, issuer, subject, email, created_at, last_login_at`
+
+func scanIdentity(row rowScanner) (*UserIdentity, error) {
+	var identity UserIdentity
+	if err := row.Scan(&identity.ID, &identity.UserID, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// GetIdentity retrieves the identity of a provider account, sql.ErrNoRows if it isn't linked
+func (r *IdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*UserIdentity, error) {
+	query :=
+		`
+		SELECT ` + identityColumns + `
+		FROM user_identities
+		WHERE issuer = $1 AND subject = $2;
+	`
+
+	return scanIdentity(r.db.QueryRowContext(ctx, query, issuer, subject))
+}
+
+// LinkIdentity links a provider account to an existing user. It returns
+// ErrIdentityLinked when the account is already linked.
+func (r *IdentityRepository) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) (*UserIdentity, error) {
+	query :=
+		`
+		INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, now())
+		RETURNING ` + identityColumns + `;
+	`
+
+	identity, err := scanIdentity(r.db.QueryRowContext(ctx, query, userID, issuer, subject, email))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrIdentityLinked
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// TouchIdentity records a sign-in with the identity, keeping its email up to date
+func (r *IdentityRepository) TouchIdentity(ctx context.Context, id uuid.UUID, email string) error {
+	query :=
+		`
+		UPDATE user_identities
+		SET email = $2, last_login_at = now()
+		WHERE id = $1;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, email)
+	return err
+}
+
+// CreateUserWithIdentity creates a user whose email the provider already verified,
+// together with their identity. An empty passwordHash creates the user without a
+// password. It returns ErrUsernameTaken, ErrEmailTaken or
+// ErrIdentityLinked when one of them already exists.
+func (r *IdentityRepository) CreateUserWithIdentity(ctx context.Context, email, username, passwordHash, issuer, subject string) (*User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user := &User{
+		Email:        email,
+		Username:     username,
+		PasswordHash: passwordHash,
+	}
+
+	userQuery :=
+		`
+		INSERT INTO users (email, username, password_hash, email_verified_at)
+		VALUES ($1, $2, $3, now())
+		RETURNING id, created_at, updated_at, email_verified_at, role;
+	`
+
+	err = tx.QueryRowContext(ctx, userQuery, user.Email, user.Username, user.PasswordHash).
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.EmailVerifiedAt, &user.Role)
+	if err != nil {
+		return nil, translateUserConflict(err)
+	}
+
+	identityQuery :=
+		`
+		INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, now());
+	`
+
+	if _, err := tx.ExecContext(ctx, identityQuery, user.ID, issuer, subject, email); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrIdentityLinked
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// translateUserConflict tells which unique column of users an insert collided with
+func translateUserConflict(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case "users_email_key":
+		return ErrEmailTaken
+	case "users_username_key":
+		return ErrUsernameTaken
+	}
+
+	return err
+}
@@ -0,0 +1,377 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobLimitReached = errors.New("job limit reached")
+	ErrJobKeyHeld      = errors.New("unique key is held by another unfinished job")
+)
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusDead      = "dead"
+)
+
+// Job represents a background job in our system
+type Job struct {
+	ID          uuid.UUID
+	Kind        string
+	Payload     json.RawMessage
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	UniqueKey   sql.NullString
+	LastError   sql.NullString
+	LockedBy    sql.NullString
+	LockedUntil sql.NullTime
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FinishedAt  sql.NullTime
+	// UserID is set for jobs a user requested
+	UserID uuid.NullUUID
+	// Result is what the job reported back, nil until it does
+	Result json.RawMessage
+}
+
+// JobRepository handles database operations for jobs
+type JobRepository struct {
+	db *sql.DB
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, unique_key, last_error, locked_by, locked_until, created_at, updated_at, finished_at, user_id, result`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var payload, result []byte
+	if err := row.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &job.UniqueKey, &job.LastError, &job.LockedBy, &job.LockedUntil, &job.CreatedAt, &job.UpdatedAt, &job.FinishedAt, &job.UserID, &result); err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	job.Result = result
+
+	return &job, nil
+}
+
+func scanJobs(rows *sql.Rows) ([]*Job, error) {
+	defer rows.Close()
+
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// CreateJob adds a new pending job. When uniqueKey is set and an unfinished job
+// already holds it, nothing is inserted and that job is returned instead;
+// created reports whether a new job was inserted.
+func (r *JobRepository) CreateJob(ctx context.Context, kind string, payload []byte, runAt time.Time, uniqueKey string, maxAttempts int) (job *Job, created bool, err error) {
+	query :=
+		`
+		INSERT INTO jobs (kind, payload, run_at, unique_key, max_attempts)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT DO NOTHING
+		RETURNING ` + jobColumns + `;
+	`
+
+	job, err = scanJob(r.db.QueryRowContext(ctx, query, kind, payload, runAt, uniqueKey, maxAttempts))
+	if err == nil {
+		return job, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) || uniqueKey == "" {
+		return nil, false, err
+	}
+
+	existingQuery :=
+		`
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE unique_key = $1 AND status IN ('pending', 'running');
+	`
+
+	job, err = scanJob(r.db.QueryRowContext(ctx, existingQuery, uniqueKey))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return job, false, nil
+}
+
+// CreateUserJob adds a new pending job requested by a user, unless the user
+// already created limit jobs of the same kind since the cutoff, in which case it
+// returns ErrJobLimitReached. Requests of the same user are serialized so
+// concurrent ones can't both slip under the limit.
+func (r *JobRepository) CreateUserJob(ctx context.Context, userID uuid.UUID, kind string, payload []byte, maxAttempts, limit int, since time.Time) (*Job, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1));`, "jobs:"+kind+":"+userID.String()); err != nil {
+		return nil, err
+	}
+
+	countQuery :=
+		`
+		SELECT count(*)
+		FROM jobs
+		WHERE user_id = $1 AND kind = $2 AND created_at > $3;
+	`
+
+	var recent int
+	if err := tx.QueryRowContext(ctx, countQuery, userID, kind, since).Scan(&recent); err != nil {
+		return nil, err
+	}
+	if recent >= limit {
+		return nil, ErrJobLimitReached
+	}
+
+	query :=
+		`
+		INSERT INTO jobs (kind, payload, max_attempts, user_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + jobColumns + `;
+	`
+
+	job, err := scanJob(tx.QueryRowContext(ctx, query, kind, payload, maxAttempts, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ClaimJobs atomically locks up to limit runnable jobs of the given kinds for
+// owner. Jobs whose lock expired (the worker running them died) are reclaimed,
+// unless that was their last attempt, then they are dead so a job that keeps
+// crashing its worker isn't run forever. Rows locked by other workers are skipped.
+func (r *JobRepository) ClaimJobs(ctx context.Context, owner string, kinds []string, limit int, lease time.Duration) ([]*Job, error) {
+	killQuery :=
+		`
+		UPDATE jobs
+		SET status = 'dead', last_error = 'lease expired on the last attempt', locked_by = NULL, locked_until = NULL,
+		    finished_at = now(), updated_at = now()
+		WHERE kind = ANY($1::text[]) AND status = 'running' AND locked_until < now() AND attempts >= max_attempts;
+	`
+
+	if _, err := r.db.ExecContext(ctx, killQuery, kinds); err != nil {
+		return nil, err
+	}
+
+	query :=
+		`
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $1,
+		    locked_until = now() + make_interval(secs => $4), updated_at = now()
+		WHERE id IN (
+			SELECT id
+			FROM jobs
+			WHERE kind = ANY($2::text[])
+			AND (
+				(status = 'pending' AND run_at <= now())
+				OR (status = 'running' AND locked_until < now() AND attempts < max_attempts)
+			)
+			ORDER BY run_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns + `;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, owner, kinds, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return scanJobs(rows)
+}
+
+// ExtendJobLease keeps a running job locked to owner for lease from now. It
+// reports false when owner no longer holds the job.
+func (r *JobRepository) ExtendJobLease(ctx context.Context, id uuid.UUID, owner string, lease time.Duration) (bool, error) {
+	query :=
+		`
+		UPDATE jobs
+		SET locked_until = now() + make_interval(secs => $3), updated_at = now()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running';
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, owner, lease.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// CompleteJob marks a job run by owner as completed
+func (r *JobRepository) CompleteJob(ctx context.Context, id uuid.UUID, owner string) error {
+	query :=
+		`
+		UPDATE jobs
+		SET status = 'completed', locked_by = NULL, locked_until = NULL, finished_at = now(), updated_at = now()
+		WHERE id = $1 AND locked_by = $2;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, owner)
+	return err
+}
+
+// UpdateJobResult stores what a job reports back, replacing any earlier result
+func (r *JobRepository) UpdateJobResult(ctx context.Context, id uuid.UUID, result []byte) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET result = $2, updated_at = now() WHERE id = $1;`, id, result)
+	return err
+}
+
+// RetryJobLater puts a failed job back in the queue to run again at runAt
+func (r *JobRepository) RetryJobLater(ctx context.Context, id uuid.UUID, owner, lastError string, runAt time.Time) error {
+	query :=
+		`
+		UPDATE jobs
+		SET status = 'pending', last_error = $3, run_at = $4, locked_by = NULL, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND locked_by = $2;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, owner, lastError, runAt)
+	return err
+}
+
+// KillJob moves a job whose retries are exhausted to the dead state
+func (r *JobRepository) KillJob(ctx context.Context, id uuid.UUID, owner, lastError string) error {
+	query :=
+		`
+		UPDATE jobs
+		SET status = 'dead', last_error = $3, locked_by = NULL, locked_until = NULL, finished_at = now(), updated_at = now()
+		WHERE id = $1 AND locked_by = $2;
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, owner, lastError)
+	return err
+}
+
+// RetryJob re-queues a dead or pending job to run immediately with a fresh set of attempts.
+// It returns sql.ErrNoRows when the job doesn't exist or is running or completed,
+// and ErrJobKeyHeld when a newer unfinished job holds its unique key.
+func (r *JobRepository) RetryJob(ctx context.Context, id uuid.UUID) (*Job, error) {
+	query :=
+		`
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = now(), finished_at = NULL, updated_at = now()
+		WHERE id = $1 AND status IN ('dead', 'pending')
+		RETURNING ` + jobColumns + `;
+	`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrJobKeyHeld
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetJobByID retrieves a job by its ID
+func (r *JobRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*Job, error) {
+	query :=
+		`
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE id = $1;
+	`
+
+	return scanJob(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetUserJob retrieves a job of the given kind requested by the user
+func (r *JobRepository) GetUserJob(ctx context.Context, userID uuid.UUID, kind string, id uuid.UUID) (*Job, error) {
+	query :=
+		`
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE id = $1 AND user_id = $2 AND kind = $3;
+	`
+
+	return scanJob(r.db.QueryRowContext(ctx, query, id, userID, kind))
+}
+
+// ListJobs retrieves jobs newest first, optionally filtered by status and kind
+func (r *JobRepository) ListJobs(ctx context.Context, status, kind string, offset, limit int) ([]*Job, error) {
+	query :=
+		`
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE ($1 = '' OR status = $1)
+		AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+		OFFSET $4;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, kind, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanJobs(rows)
+}
+
+// DeleteFinishedJobs removes completed and dead jobs that finished before the cutoff
+func (r *JobRepository) DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	query :=
+		`
+		DELETE FROM jobs
+		WHERE status IN ('completed', 'dead') AND finished_at < $1;
+	`
+
+	res, err := r.db.ExecContext(ctx, query, finishedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+)
+
+// TOTPSettings is the encrypted TOTP secret of a user and the state needed to validate codes
+type TOTPSettings struct {
+	UserID          uuid.UUID
+	EncryptedSecret []byte
+	Enabled         bool
+	// LastStep is the time step of the last accepted code
+	LastStep int64
+}
+
+// MFARepository handles database operations for two-factor authentication
+type MFARepository struct {
+	db *sql.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{
+		db: db,
+	}
+}
+
+// SetPendingTOTPSecret stores a new secret awaiting confirmation, replacing any
+// earlier unconfirmed one. It returns ErrTOTPAlreadyEnabled when TOTP is on.
+func (r *MFARepository) SetPendingTOTPSecret(ctx context.Context, userID uuid.UUID, encryptedSecret []byte) error {
+	query :=
+		`
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = 0, updated_at = now()
+		WHERE id = $1 AND totp_enabled_at IS NULL;
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, encryptedSecret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// GetTOTPSettings retrieves the user's TOTP secret, sql.ErrNoRows when they have none
+func (r *MFARepository) GetTOTPSettings(ctx context.Context, userID uuid.UUID) (*TOTPSettings, error) {
+	query :=
+		`
+		SELECT id, totp_secret, totp_enabled_at IS NOT NULL, totp_last_step
+		FROM users
+		WHERE id = $1 AND totp_secret IS NOT NULL;
+	`
+
+	var settings TOTPSettings
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&settings.UserID, &settings.EncryptedSecret, &settings.Enabled, &settings.LastStep)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// UseTOTPStep records that the code of step was accepted. It reports false when
+// a code of this or a later step was already used, so the code is a replay.
+func (r *MFARepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query :=
+		`
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND totp_last_step < $2;
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// EnableTOTP turns on the pending secret and replaces the user's recovery codes
+// with the given digests. The code of step was used to confirm it.
+func (r *MFARepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+		UPDATE users
+		SET totp_enabled_at = now(), totp_last_step = $2, updated_at = now()
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL AND totp_last_step < $2;
+	`
+
+	res, err := tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP turns two-factor authentication off and drops the secret and recovery codes
+func (r *MFARepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = now()
+		WHERE id = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of the user with the given digests
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, codeHashes [][]byte) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+
+	query :=
+		`
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::bytea[]);
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID, codeHashes)
+	return err
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used, reporting
+// whether there was one with this digest
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash []byte) (bool, error) {
+	query :=
+		`
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL;`, userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateLimitRepository stores the recent events of rate limits, so a limit
+// holds across every API instance
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+// NewRateLimitRepository creates a new rate limit repository
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// UpdateRateLimit locks the events recorded for key and replaces them with the
+// ones returned by update, keeping the row until expiresAt. Updates of the same
+// key wait for each other. It returns whether update allowed the event.
+func (r *RateLimitRepository) UpdateRateLimit(ctx context.Context, key string, expiresAt time.Time, update func(events []time.Time) ([]time.Time, bool)) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Creating the row first gives FOR UPDATE something to lock for new keys
+	query :=
+		`
+		INSERT INTO rate_limits (key, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO NOTHING;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, key, expiresAt); err != nil {
+		return false, err
+	}
+
+	var events []time.Time
+	if err := tx.QueryRowContext(ctx, `SELECT events FROM rate_limits WHERE key = $1 FOR UPDATE;`, key).Scan(pgtype.NewMap().SQLScanner(&events)); err != nil {
+		return false, err
+	}
+
+	events, allowed := update(events)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE rate_limits SET events = $2, expires_at = $3 WHERE key = $1;`, key, events, expiresAt); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return allowed, nil
+}
+
+// DeleteExpiredRateLimits deletes up to limit rate limits without events newer
+// than the given time, returning how many were deleted
+func (r *RateLimitRepository) DeleteExpiredRateLimits(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	query :=
+		`
+		DELETE FROM rate_limits
+		WHERE key IN (
+			SELECT key
+			FROM rate_limits
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, expiredBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -1,15 +1,20 @@
 package models
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Refresh Token represents	a refresh token in the system
+// Refresh Token represents	a refresh token in the system.
+// Only a SHA-256 digest of Token is stored, so Token is the raw value
+// handed to the client or the one the token was looked up with.
 type RefreshToken struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
@@ -18,6 +23,24 @@ type RefreshToken struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Revoked   bool
+	SessionID uuid.NullUUID
+	// FamilyID is the ID of the first token of the rotation chain
+	FamilyID  uuid.UUID
+	RotatedAt sql.NullTime
+}
+
+var (
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// hashRefreshToken returns the digest refresh tokens are stored and looked up by.
+// Tokens carry 256 random bits, so a plain SHA-256 can't be brute forced.
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
 }
 
 // RefreshTokenRepository handles database operations for refresh tokens
@@ -32,8 +55,8 @@ func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
 	}
 }
 
-// CreateRefreshToken creates a new refresh token for a user
-func (r *RefreshTokenRepository) CreateRefreshToken(userID uuid.UUID, ttl time.Duration) (*RefreshToken, error) {
+// CreateRefreshToken creates the first refresh token of a session, starting a new token family
+func (r *RefreshTokenRepository) CreateRefreshToken(userID, sessionID uuid.UUID, ttl time.Duration) (*RefreshToken, error) {
 	// Generate a unique token identifier
 	tokenID := uuid.New()
 	// tokenString := tokenID.String()
@@ -48,16 +71,18 @@ func (r *RefreshTokenRepository) CreateRefreshToken(userID uuid.UUID, ttl time.D
 		UserID:    userID,
 		Token:     tokenString,
 		ExpiresAt: expiresAt,
+		SessionID: uuid.NullUUID{UUID: sessionID, Valid: true},
+		FamilyID:  tokenID,
 	}
 
 	query :=
 		`
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, session_id, family_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING created_at, updated_at, revoked;
 	`
 
-	err := r.db.QueryRow(query, token.ID, token.UserID, token.Token, token.ExpiresAt).Scan(&token.CreatedAt, &token.UpdatedAt, &token.Revoked)
+	err := r.db.QueryRow(query, token.ID, token.UserID, hashRefreshToken(token.Token), token.ExpiresAt, sessionID, token.FamilyID).Scan(&token.CreatedAt, &token.UpdatedAt, &token.Revoked)
 	if err != nil {
 		return nil, err
 	}
@@ -69,13 +94,15 @@ func (r *RefreshTokenRepository) CreateRefreshToken(userID uuid.UUID, ttl time.D
 func (r *RefreshTokenRepository) GetRefreshToken(tokenString string) (*RefreshToken, error) {
 	query :=
 		`
-		SELECT id, user_id, token, expires_at, created_at, updated_at, revoked
+		SELECT id, user_id, expires_at, created_at, updated_at, revoked, session_id, family_id, rotated_at
 		FROM refresh_tokens
-		WHERE token = $1;
+		WHERE token_hash = $1;
 	`
 
-	var token RefreshToken
-	err := r.db.QueryRow(query, tokenString).Scan(&token.ID, &token.UserID, &token.Token, &token.ExpiresAt, &token.CreatedAt, &token.UpdatedAt, &token.Revoked)
+	token := RefreshToken{
+		Token: tokenString,
+	}
+	err := r.db.QueryRow(query, hashRefreshToken(tokenString)).Scan(&token.ID, &token.UserID, &token.ExpiresAt, &token.CreatedAt, &token.UpdatedAt, &token.Revoked, &token.SessionID, &token.FamilyID, &token.RotatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -89,16 +116,23 @@ func (r *RefreshTokenRepository) RevokeRefreshToken(tokenString string) error {
 		`
 		UPDATE refresh_tokens
 		SET revoked = true, updated_at = NOW()
-		WHERE token = $1;
+		WHERE token_hash = $1;
 	`
 
-	_, err := r.db.Exec(query, tokenString)
+	_, err := r.db.Exec(query, hashRefreshToken(tokenString))
 	return err
 }
 
-// RotateRefreshToken atomically creates a new refresh token for the same user and revokes the old one.
-// Returns the new RefreshToken or an error (sql.ErrNoRows if old token not found).
-func (r *RefreshTokenRepository) RotateRefreshToken(oldTokenString string, ttl time.Duration) (*RefreshToken, error) {
+// RotateRefreshToken atomically creates a new refresh token in the same session and family and
+// revokes the old one. The session's last use is recorded with the client's user agent and IP address.
+// Returns the new RefreshToken or an error (sql.ErrNoRows if old token not found,
+// ErrSessionRevoked if its session was signed out, ErrRefreshTokenRevoked or
+// ErrRefreshTokenExpired if the old token can no longer be used).
+//
+// Presenting a token that was already rotated returns ErrRefreshTokenReused after
+// revoking its whole family and session, since either the client or an attacker
+// holds a stolen copy and we can't tell which one.
+func (r *RefreshTokenRepository) RotateRefreshToken(oldTokenString string, ttl time.Duration, userAgent, ipAddress string) (*RefreshToken, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
@@ -113,21 +147,49 @@ func (r *RefreshTokenRepository) RotateRefreshToken(oldTokenString string, ttl t
 
 	var userID uuid.UUID
 	var revoked bool
+	var rotated bool
 	var expiresAt time.Time
+	var sessionID uuid.NullUUID
+	var familyID uuid.UUID
+	var sessionRevoked bool
 
-	// Retrieve the old token's user ID
+	// Retrieve the old token's user ID, session and family
 	query := `
-		SELECT user_id, revoked, expires_at
-		FROM refresh_tokens
-		WHERE token = $1
-		FOR UPDATE;
+		SELECT rt.user_id, rt.revoked, rt.rotated_at IS NOT NULL, rt.expires_at, rt.session_id, rt.family_id,
+		       s.id IS NULL OR s.revoked_at IS NOT NULL
+		FROM refresh_tokens rt
+		LEFT JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt;
 	`
 
-	err = tx.QueryRow(query, oldTokenString).Scan(&userID, &revoked, &expiresAt)
+	oldTokenHash := hashRefreshToken(oldTokenString)
+	err = tx.QueryRow(query, oldTokenHash).Scan(&userID, &revoked, &rotated, &expiresAt, &sessionID, &familyID, &sessionRevoked)
 	if err != nil {
 		return nil, err
 	}
 
+	switch {
+	case sessionRevoked:
+		err = ErrSessionRevoked
+		return nil, err
+	case rotated:
+		if err = revokeTokenFamily(tx, familyID, sessionID); err != nil {
+			return nil, err
+		}
+		// Keep the revocation, the caller still gets an error
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	case revoked:
+		err = ErrRefreshTokenRevoked
+		return nil, err
+	case !expiresAt.After(time.Now()):
+		err = ErrRefreshTokenExpired
+		return nil, err
+	}
+
 	// Create a new refresh token
 	newTokenID := uuid.New()
 	tokenBytes := make([]byte, 32)
@@ -139,8 +201,8 @@ func (r *RefreshTokenRepository) RotateRefreshToken(oldTokenString string, ttl t
 
 	insertQuery :=
 		`
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, session_id, family_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING created_at, updated_at, revoked;
 	`
 
@@ -149,20 +211,34 @@ func (r *RefreshTokenRepository) RotateRefreshToken(oldTokenString string, ttl t
 	newToken.UserID = userID
 	newToken.Token = newTokenString
 	newToken.ExpiresAt = newExpiresAt
+	newToken.SessionID = sessionID
+	newToken.FamilyID = familyID
 
-	if err = tx.QueryRow(insertQuery, newToken.ID, newToken.UserID, newToken.Token, newToken.ExpiresAt).Scan(&newToken.CreatedAt, &newToken.UpdatedAt, &newToken.Revoked); err != nil {
+	if err = tx.QueryRow(insertQuery, newToken.ID, newToken.UserID, hashRefreshToken(newToken.Token), newToken.ExpiresAt, newToken.SessionID, newToken.FamilyID).Scan(&newToken.CreatedAt, &newToken.UpdatedAt, &newToken.Revoked); err != nil {
 		return nil, err
 	}
 
-	// Revoke the old token
+	// Revoke the old token, remembering it was rotated so a later use is caught as reuse
 	updateQuery :=
 		`
 		UPDATE refresh_tokens
-		SET revoked = true, updated_at = NOW()
-		WHERE token = $1;
+		SET revoked = true, rotated_at = NOW(), updated_at = NOW()
+		WHERE token_hash = $1;
 	`
 
-	if _, err = tx.Exec(updateQuery, oldTokenString); err != nil {
+	if _, err = tx.Exec(updateQuery, oldTokenHash); err != nil {
+		return nil, err
+	}
+
+	// The session now lives as long as its newest token
+	sessionQuery :=
+		`
+		UPDATE sessions
+		SET last_used_at = now(), expires_at = $2, user_agent = $3, ip_address = $4
+		WHERE id = $1;
+	`
+
+	if _, err = tx.Exec(sessionQuery, sessionID, newExpiresAt, userAgent, ipAddress); err != nil {
 		return nil, err
 	}
 
@@ -185,3 +261,54 @@ func (r *RefreshTokenRepository) RevokeAllForUser(userID uuid.UUID) error {
 
 	return err
 }
+
+// revokeTokenFamily revokes every token of a family and signs out the session holding it
+func revokeTokenFamily(tx *sql.Tx, familyID uuid.UUID, sessionID uuid.NullUUID) error {
+	query :=
+		`
+		UPDATE refresh_tokens
+		SET revoked = true, updated_at = NOW()
+		WHERE family_id = $1 AND revoked = false;
+	`
+
+	if _, err := tx.Exec(query, familyID); err != nil {
+		return err
+	}
+
+	if !sessionID.Valid {
+		return nil
+	}
+
+	sessionQuery :=
+		`
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL;
+	`
+
+	_, err := tx.Exec(sessionQuery, sessionID)
+	return err
+}
+
+// DeleteExpiredRefreshTokens deletes up to limit refresh tokens that expired
+// before the given time, returning how many were deleted
+func (r *RefreshTokenRepository) DeleteExpiredRefreshTokens(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	query :=
+		`
+		DELETE FROM refresh_tokens
+		WHERE id IN (
+			SELECT id
+			FROM refresh_tokens
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, expiredBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
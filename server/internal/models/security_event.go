@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of security events
+const (
+	// SecurityEventRefreshTokenReuse is recorded when an already rotated refresh
+	// token is presented again, which means it was copied from the client
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	// SecurityEventPasswordReset is recorded when a password is reset through an emailed link
+	SecurityEventPasswordReset = "password_reset"
+	// SecurityEventPasswordChanged is recorded when a signed-in user changes their password
+	SecurityEventPasswordChanged = "password_changed"
+	// SecurityEventEmailChanged is recorded when a user changes their email address
+	SecurityEventEmailChanged = "email_changed"
+	// SecurityEventIdentityLinked is recorded when an external sign-in provider is linked to an account
+	SecurityEventIdentityLinked = "identity_linked"
+	// SecurityEventRoleChanged is recorded when an admin changes the role of a user
+	SecurityEventRoleChanged = "role_changed"
+)
+
+// SecurityEvent records something suspicious that happened to a user's account
+type SecurityEvent struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	SessionID uuid.NullUUID
+	UserAgent string
+	IPAddress string
+	CreatedAt time.Time
+}
+
+// SecurityEventRepository handles database operations for security events
+type SecurityEventRepository struct {
+	db *sql.DB
+}
+
+// NewSecurityEventRepository creates a new security event repository
+func NewSecurityEventRepository(db *sql.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		db: db,
+	}
+}
+
+// CreateSecurityEvent records a security event, filling in its ID and creation time
+func (r *SecurityEventRepository) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	query :=
+		`
+		INSERT INTO security_events (user_id, kind, session_id, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at;
+	`
+
+	return r.db.QueryRowContext(ctx, query, event.UserID, event.Kind, event.SessionID, event.UserAgent, event.IPAddress).Scan(&event.ID, &event.CreatedAt)
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session represents a signed-in device of a user
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+}
+
+// SessionRepository handles database operations for sessions
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+const sessionColumns = `id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*Session, error) {
+	var session Session
+	if err := row.Scan(&session.ID, &session.UserID, &session.DeviceName, &session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// CreateSession starts a new session for a user
+func (r *SessionRepository) CreateSession(ctx context.Context, userID uuid.UUID, deviceName, userAgent, ipAddress string, expiresAt time.Time) (*Session, error) {
+	query :=
+		`
+		INSERT INTO sessions (user_id, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + sessionColumns + `;
+	`
+
+	return scanSession(r.db.QueryRowContext(ctx, query, userID, deviceName, userAgent, ipAddress, expiresAt))
+}
+
+// DeleteSession removes a session and its refresh tokens
+func (r *SessionRepository) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1;`, id)
+	return err
+}
+
+// ListActiveSessions retrieves the user's sessions that are neither revoked nor expired, most recently used first
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	query :=
+		`
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_used_at DESC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes an active session of the user together with its
+// refresh tokens. It returns sql.ErrNoRows when the user has no such active session.
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+	`
+
+	res, err := tx.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := revokeSessionTokens(ctx, tx, []uuid.UUID{id}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeOtherSessions revokes every active session of the user except keepID,
+// returning how many sessions were revoked
+func (r *SessionRepository) RevokeOtherSessions(ctx context.Context, userID, keepID uuid.UUID) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id;
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := revokeSessionTokens(ctx, tx, ids); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
+
+// RevokeAllSessions revokes every active session of the user, returning how many were revoked
+func (r *SessionRepository) RevokeAllSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	// No session has the nil ID, so none is kept
+	return r.RevokeOtherSessions(ctx, userID, uuid.Nil)
+}
+
+// revokeSessionTokens revokes the refresh tokens of the given sessions
+func revokeSessionTokens(ctx context.Context, tx *sql.Tx, sessionIDs []uuid.UUID) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		ids = append(ids, id.String())
+	}
+
+	query :=
+		`
+		UPDATE refresh_tokens
+		SET revoked = true, updated_at = now()
+		WHERE session_id = ANY($1::uuid[]) AND revoked = false;
+	`
+
+	_, err := tx.ExecContext(ctx, query, ids)
+	return err
+}
@@ -1,12 +1,36 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+var (
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already taken")
+	ErrLastAdmin     = errors.New("cannot remove the last admin")
+)
+
+// Roles of users, every user has exactly one
+const (
+	// RoleUser reads and subscribes to feeds
+	RoleUser = "user"
+	// RoleAdmin also manages global feeds, users and background jobs
+	RoleAdmin = "admin"
+)
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate value
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 // User represents a user in our system
 type User struct {
 	ID           uuid.UUID
@@ -16,6 +40,35 @@ type User struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	LastLogin    sql.NullTime
+	// EmailVerifiedAt is set once the user proved they own Email
+	EmailVerifiedAt sql.NullTime
+	// PendingEmail replaces Email once the user verified it
+	PendingEmail sql.NullString
+	// TOTPEnabledAt is set while two-factor authentication is on
+	TOTPEnabledAt sql.NullTime
+	// Role is RoleUser or RoleAdmin
+	Role string
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// TOTPEnabled reports whether the user signs in with a second factor
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt.Valid
+}
+
+// HasPassword reports whether the user can sign in with a password, accounts
+// created through single sign-on have none until they reset it
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// EmailVerified reports whether the user verified their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt.Valid
 }
 
 // UserRepository handles database operations for users
@@ -40,11 +93,11 @@ func (r *UserRepository) CreateUser(email, username, passwordHash string) (*User
 		`
     INSERT INTO users (email, username, password_hash)
     VALUES ($1, $2, $3)
-    RETURNING id, created_at, updated_at;
+    RETURNING id, created_at, updated_at, role;
   `
 
 	err := r.db.QueryRow(query, user.Email, user.Username, user.PasswordHash).
-		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -56,14 +109,14 @@ func (r *UserRepository) CreateUser(email, username, passwordHash string) (*User
 func (r *UserRepository) GetUserByEmail(email string) (*User, error) {
 	query :=
 		`
-		SELECT id, email, username, password_hash, created_at, updated_at, last_login
+		SELECT id, email, username, password_hash, created_at, updated_at, last_login, email_verified_at, pending_email, totp_enabled_at, role
 		FROM users
 		WHERE email = $1;
 	`
 
 	var user User
 
-	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin)
+	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin, &user.EmailVerifiedAt, &user.PendingEmail, &user.TOTPEnabledAt, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -75,13 +128,13 @@ func (r *UserRepository) GetUserByEmail(email string) (*User, error) {
 func (r *UserRepository) GetUserByID(id uuid.UUID) (*User, error) {
 	query :=
 		`
-		SELECT id, email, username, password_hash, created_at, updated_at, last_login
+		SELECT id, email, username, password_hash, created_at, updated_at, last_login, email_verified_at, pending_email, totp_enabled_at, role
 		FROM users
 		WHERE id = $1; 
 	`
 
 	var user User
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin)
+	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin, &user.EmailVerifiedAt, &user.PendingEmail, &user.TOTPEnabledAt, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -101,3 +154,382 @@ func (r *UserRepository) UpdateLastLogin(userId uuid.UUID) error {
 	_, err := r.db.Exec(query, userId)
 	return err
 }
+
+// VerifyEmail marks email as verified for the user. When it is their pending
+// email it replaces the current one. It reports false when email is neither,
+// and returns ErrEmailTaken when another user took the pending email meanwhile.
+func (r *UserRepository) VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	query :=
+		`
+		UPDATE users
+		SET email = $2,
+		    pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END,
+		    email_verified_at = CASE WHEN email = $2 THEN COALESCE(email_verified_at, now()) ELSE now() END,
+		    updated_at = now()
+		WHERE id = $1 AND (email = $2 OR pending_email = $2);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, ErrEmailTaken
+		}
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+// ResetPasswordWithToken consumes an unused, unexpired password reset token and
+// sets a new password for its user, verifying the email as the link proved access
+// to it. In the same transaction every session and refresh token of the user is
+// revoked and their personal access tokens are deleted, so a failure leaves no
+// step half done and the token still usable. It returns sql.ErrNoRows when the
+// token is invalid or the user's email changed since it was sent.
+func (r *UserRepository) ResetPasswordWithToken(ctx context.Context, tokenHash []byte, passwordHash string) (*UserToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tokenQuery :=
+		`
+		UPDATE user_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING ` + userTokenColumns + `;
+	`
+
+	token, err := scanUserToken(tx.QueryRowContext(ctx, tokenQuery, tokenHash, UserTokenPasswordReset))
+	if err != nil {
+		return nil, err
+	}
+
+	passwordQuery :=
+		`
+		UPDATE users
+		SET password_hash = $3, email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND email = $2;
+	`
+
+	res, err := tx.ExecContext(ctx, passwordQuery, token.UserID, token.Email, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	revokeQueries := []string{
+		`UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`,
+		// Tokens issued before sessions existed don't belong to one
+		`UPDATE refresh_tokens SET revoked = true, updated_at = now() WHERE user_id = $1 AND revoked = false;`,
+		`DELETE FROM api_tokens WHERE user_id = $1;`,
+	}
+	for _, query := range revokeQueries {
+		if _, err := tx.ExecContext(ctx, query, token.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// UpdateUsername changes the user's username. It returns ErrUsernameTaken when
+// another user has it and sql.ErrNoRows when the user doesn't exist.
+func (r *UserRepository) UpdateUsername(ctx context.Context, userID uuid.UUID, username string) error {
+	query :=
+		`
+		UPDATE users
+		SET username = $2, updated_at = now()
+		WHERE id = $1;
+	`
+
+	return r.updateUser(ctx, query, ErrUsernameTaken, userID, username)
+}
+
+// ChangePassword replaces the user's password hash and, in the same transaction,
+// revokes every session but keepSessionID along with their refresh tokens and
+// deletes the user's personal access tokens. It returns how many sessions were
+// revoked, or sql.ErrNoRows when the user doesn't exist.
+func (r *UserRepository) ChangePassword(ctx context.Context, userID, keepSessionID uuid.UUID, passwordHash string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = $2, updated_at = now() WHERE id = $1;`, userID, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	sessionQuery :=
+		`
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id;
+	`
+
+	sessionRows, err := tx.QueryContext(ctx, sessionQuery, userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, 0)
+	for sessionRows.Next() {
+		var id uuid.UUID
+		if err := sessionRows.Scan(&id); err != nil {
+			sessionRows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	sessionRows.Close()
+	if err := sessionRows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := revokeSessionTokens(ctx, tx, ids); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id = $1;`, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
+
+// SetPendingEmail stores the email the user wants to move to, replacing any
+// earlier one. It returns ErrEmailTaken when another user has that email and
+// sql.ErrNoRows when the user doesn't exist.
+func (r *UserRepository) SetPendingEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	var taken bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1);`, email).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	query :=
+		`
+		UPDATE users
+		SET pending_email = $2, updated_at = now()
+		WHERE id = $1;
+	`
+
+	return r.updateUser(ctx, query, nil, userID, email)
+}
+
+// updateUser runs an update of a single user, translating duplicates to errTaken
+func (r *UserRepository) updateUser(ctx context.Context, query string, errTaken error, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		if errTaken != nil && isUniqueViolation(err) {
+			return errTaken
+		}
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user. Their sessions, tokens, subscriptions and article
+// states go with them, feeds are shared and stay until the orphan collector
+// removes the ones nobody subscribes to anymore. Deleting the only admin fails
+// with ErrLastAdmin.
+func (r *UserRepository) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := ensureOtherAdmin(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1;`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// ListUsers retrieves a page of users ordered by email, along with the total number of users
+func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]*User, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM users;`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query :=
+		`
+		SELECT id, email, username, password_hash, created_at, updated_at, last_login, email_verified_at, pending_email, totp_enabled_at, role
+		FROM users
+		ORDER BY email
+		LIMIT $1 OFFSET $2;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin, &user.EmailVerifiedAt, &user.PendingEmail, &user.TOTPEnabledAt, &user.Role); err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SetUserRole changes the role of a user. Demoting the only admin fails with
+// ErrLastAdmin, a missing user with sql.ErrNoRows.
+func (r *UserRepository) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if role != RoleAdmin {
+		if err := ensureOtherAdmin(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET role = $2, updated_at = now() WHERE id = $1;`, userID, role)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// ensureOtherAdmin returns ErrLastAdmin when userID is the only admin. The admins
+// are locked, so two admins can't demote each other at the same time.
+func ensureOtherAdmin(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM users WHERE role = 'admin' FOR UPDATE;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	isAdmin, others := false, 0
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		if id == userID {
+			isAdmin = true
+		} else {
+			others++
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if isAdmin && others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// PromoteAdmins gives the admin role to the users with a verified email among
+// emails, as long as there is no admin yet, returning how many users were
+// promoted. Once any admin exists it changes nothing, so demoted users stay demoted.
+func (r *UserRepository) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	query :=
+		`
+		UPDATE users
+		SET role = 'admin', updated_at = now()
+		WHERE lower(email) = ANY($1::text[]) AND email_verified_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin');
+	`
+
+	lowered := make([]string, 0, len(emails))
+	for _, email := range emails {
+		lowered = append(lowered, strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	res, err := r.db.ExecContext(ctx, query, lowered)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes of user tokens, a token only works for the purpose it was issued for
+const (
+	UserTokenEmailVerification = "email_verification"
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenStreamTicket      = "stream_ticket"
+)
+
+// UserToken is a single-use token issued to a user, such as an email verification
+// link or a stream ticket
+type UserToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+// UserTokenRepository handles database operations for user tokens
+type UserTokenRepository struct {
+	db *sql.DB
+}
+
+// NewUserTokenRepository creates a new user token repository
+func NewUserTokenRepository(db *sql.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		db: db,
+	}
+}
+
+const userTokenColumns = `id, user_id, purpose, email, created_at, expires_at, used_at`
+
+func scanUserToken(row rowScanner) (*UserToken, error) {
+	var token UserToken
+	if err := row.Scan(&token.ID, &token.UserID, &token.Purpose, &token.Email, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// CreateUserToken stores the digest of a new token sent to email. Unused tokens
+// the user holds for the same purpose stop working, so only the latest email counts.
+func (r *UserTokenRepository) CreateUserToken(ctx context.Context, userID uuid.UUID, purpose string, tokenHash []byte, email string, expiresAt time.Time) (*UserToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invalidateQuery :=
+		`
+		UPDATE user_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+	`
+
+	if _, err := tx.ExecContext(ctx, invalidateQuery, userID, purpose); err != nil {
+		return nil, err
+	}
+
+	insertQuery :=
+		`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + userTokenColumns + `;
+	`
+
+	token, err := scanUserToken(tx.QueryRowContext(ctx, insertQuery, userID, purpose, tokenHash, email, expiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// AddUserToken stores the digest of a new token without invalidating the user's
+// other tokens for purpose, for tokens like stream tickets that several devices
+// hold at once. email may be empty for tokens that aren't emailed.
+func (r *UserTokenRepository) AddUserToken(ctx context.Context, userID uuid.UUID, purpose string, tokenHash []byte, email string, expiresAt time.Time) (*UserToken, error) {
+	query :=
+		`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + userTokenColumns + `;
+	`
+
+	return scanUserToken(r.db.QueryRowContext(ctx, query, userID, purpose, tokenHash, email, expiresAt))
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+// It returns sql.ErrNoRows when there is no such token.
+func (r *UserTokenRepository) ConsumeUserToken(ctx context.Context, purpose string, tokenHash []byte) (*UserToken, error) {
+	query :=
+		`
+		UPDATE user_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING ` + userTokenColumns + `;
+	`
+
+	return scanUserToken(r.db.QueryRowContext(ctx, query, tokenHash, purpose))
+}
+
+// GetLatestUserToken retrieves the token most recently issued to the user for purpose
+func (r *UserTokenRepository) GetLatestUserToken(ctx context.Context, userID uuid.UUID, purpose string) (*UserToken, error) {
+	query :=
+		`
+		SELECT ` + userTokenColumns + `
+		FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2
+		ORDER BY created_at DESC
+		LIMIT 1;
+	`
+
+	return scanUserToken(r.db.QueryRowContext(ctx, query, userID, purpose))
+}
+
+// DeleteExpiredUserTokens deletes up to limit tokens that expired before the
+// given time, returning how many were deleted
+func (r *UserTokenRepository) DeleteExpiredUserTokens(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	query :=
+		`
+		DELETE FROM user_tokens
+		WHERE id IN (
+			SELECT id
+			FROM user_tokens
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		);
+	`
+
+	res, err := r.db.ExecContext(ctx, query, expiredBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,96 @@
+package opml
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// folderNode collects the outlines of a folder while the export tree is built
+type folderNode struct {
+	name    string
+	feeds   []Outline
+	folders []*folderNode
+	index   map[string]*folderNode
+}
+
+func newFolderNode(name string) *folderNode {
+	return &folderNode{
+		name:  name,
+		index: make(map[string]*folderNode),
+	}
+}
+
+// child returns the sub folder with the given name, creating it if needed
+func (n *folderNode) child(name string) *folderNode {
+	if node, ok := n.index[name]; ok {
+		return node
+	}
+
+	node := newFolderNode(name)
+	n.index[name] = node
+	n.folders = append(n.folders, node)
+	return node
+}
+
+// outlines converts the node into outlines, folders first followed by feeds
+func (n *folderNode) outlines() []Outline {
+	outlines := make([]Outline, 0, len(n.folders)+len(n.feeds))
+	for _, folder := range n.folders {
+		outlines = append(outlines, Outline{
+			Text:     folder.name,
+			Title:    folder.name,
+			Outlines: folder.outlines(),
+		})
+	}
+
+	return append(outlines, n.feeds...)
+}
+
+// Export builds an OPML 2.0 document of the user's subscriptions, with folders
+// as nested outlines and custom titles as the outline text
+func (s *OPMLService) Export(userID uuid.UUID) (*Document, error) {
+	subscribedFeeds, err := s.feedService.GetUserSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	root := newFolderNode("")
+	for _, sf := range subscribedFeeds {
+		title := sf.Subscription.CustomTitle
+		if title == "" {
+			title = sf.Feed.Title
+		}
+		if title == "" {
+			title = sf.Feed.FeedURL
+		}
+
+		node := root
+		for _, name := range strings.Split(sf.Subscription.Folder, "/") {
+			if name = strings.TrimSpace(name); name != "" {
+				node = node.child(name)
+			}
+		}
+
+		node.feeds = append(node.feeds, Outline{
+			Text:        title,
+			Title:       title,
+			Type:        "rss",
+			XMLURL:      sf.Feed.FeedURL,
+			HTMLURL:     sf.Feed.SiteURL,
+			Description: sf.Feed.Description,
+		})
+	}
+
+	return &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       "Swayamsevak subscriptions",
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+		Body: Body{
+			Outlines: root.outlines(),
+		},
+	}, nil
+}
@@ -0,0 +1,132 @@
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	ErrInvalidDocument = errors.New("invalid opml document")
+)
+
+// Document represents an OPML 1.0/2.0 document
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head holds the document metadata
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Body holds the top level outlines
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed (it has an xmlUrl) or a folder containing more outlines
+type Outline struct {
+	Text        string    `xml:"text,attr"`
+	Title       string    `xml:"title,attr,omitempty"`
+	Type        string    `xml:"type,attr,omitempty"`
+	XMLURL      string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL     string    `xml:"htmlUrl,attr,omitempty"`
+	Description string    `xml:"description,attr,omitempty"`
+	Outlines    []Outline `xml:"outline"`
+}
+
+// Entry is a single feed outline flattened out of the document hierarchy
+type Entry struct {
+	Text        string `json:"text"`
+	Title       string `json:"title,omitempty"`
+	FeedURL     string `json:"feed_url"`
+	SiteURL     string `json:"site_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Folder      string `json:"folder,omitempty"`
+}
+
+// Parse decodes an OPML document from r
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+
+	decoder := xml.NewDecoder(r)
+	// Many exporters declare ISO-8859-1 or Windows-1252, decode those to UTF-8
+	// and reject charsets we don't know
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+
+	return &doc, nil
+}
+
+// Write encodes the document as indented XML, including the XML declaration
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Entries flattens the outline tree into feed entries. Outlines without an
+// xmlUrl but with children are treated as folders, nested folders are joined with "/".
+func (d *Document) Entries() []Entry {
+	entries := make([]Entry, 0)
+	for _, outline := range d.Body.Outlines {
+		entries = collectEntries(entries, outline, nil)
+	}
+
+	return entries
+}
+
+func collectEntries(entries []Entry, outline Outline, path []string) []Entry {
+	if outline.XMLURL == "" && len(outline.Outlines) > 0 {
+		name := folderName(outline)
+		if name != "" {
+			path = append(path[:len(path):len(path)], name)
+		}
+
+		for _, child := range outline.Outlines {
+			entries = collectEntries(entries, child, path)
+		}
+
+		return entries
+	}
+
+	return append(entries, Entry{
+		Text:        strings.TrimSpace(outline.Text),
+		Title:       strings.TrimSpace(outline.Title),
+		FeedURL:     strings.TrimSpace(outline.XMLURL),
+		SiteURL:     strings.TrimSpace(outline.HTMLURL),
+		Description: strings.TrimSpace(outline.Description),
+		Folder:      strings.Join(path, "/"),
+	})
+}
+
+func folderName(outline Outline) string {
+	name := strings.TrimSpace(outline.Text)
+	if name == "" {
+		name = strings.TrimSpace(outline.Title)
+	}
+
+	// "/" is the folder separator, keep it out of individual folder names
+	return strings.ReplaceAll(name, "/", "-")
+}
@@ -0,0 +1,113 @@
+package opml
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []Entry
+	}{
+		{
+			"flat",
+			`<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0"><body>
+  <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+</body></opml>`,
+			[]Entry{{Text: "Go Blog", FeedURL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog"}},
+		},
+		{
+			"without declaration",
+			`<opml version="1.0"><body><outline text="Feed" xmlUrl="https://example.com/feed"/></body></opml>`,
+			[]Entry{{Text: "Feed", FeedURL: "https://example.com/feed"}},
+		},
+		{
+			"trims attributes",
+			`<opml version="2.0"><body><outline text=" Feed " title=" Title " xmlUrl=" https://example.com/feed "/></body></opml>`,
+			[]Entry{{Text: "Feed", Title: "Title", FeedURL: "https://example.com/feed"}},
+		},
+		{
+			"nested folders",
+			`<opml version="2.0"><body>
+  <outline text="Tech">
+    <outline title="Go">
+      <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+    </outline>
+    <outline text="HN" xmlUrl="https://news.ycombinator.com/rss"/>
+  </outline>
+</body></opml>`,
+			[]Entry{
+				{Text: "Go Blog", FeedURL: "https://go.dev/blog/feed.atom", Folder: "Tech/Go"},
+				{Text: "HN", FeedURL: "https://news.ycombinator.com/rss", Folder: "Tech"},
+			},
+		},
+		{
+			"slash in folder name",
+			`<opml version="2.0"><body><outline text="News/Politics"><outline text="Feed" xmlUrl="https://example.com/feed"/></outline></body></opml>`,
+			[]Entry{{Text: "Feed", FeedURL: "https://example.com/feed", Folder: "News-Politics"}},
+		},
+		{
+			"outline without url",
+			`<opml version="2.0"><body><outline text="Empty"/></body></opml>`,
+			[]Entry{{Text: "Empty"}},
+		},
+		{
+			"empty body",
+			`<opml version="2.0"><body></body></opml>`,
+			[]Entry{},
+		},
+		{
+			"iso-8859-1",
+			"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<opml version=\"1.0\"><body><outline text=\"Caf\xe9 cr\xe8me\" xmlUrl=\"https://example.com/feed\"/></body></opml>",
+			[]Entry{{Text: "Café crème", FeedURL: "https://example.com/feed"}},
+		},
+		{
+			"windows-1252",
+			"<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n<opml version=\"1.0\"><body><outline text=\"\x93Quoted\x94 \x80\" xmlUrl=\"https://example.com/feed\"/></body></opml>",
+			[]Entry{{Text: "“Quoted” €", FeedURL: "https://example.com/feed"}},
+		},
+		{
+			"charset label case",
+			"<?xml version=\"1.0\" encoding=\"latin1\"?>\n<opml version=\"1.0\"><body><outline text=\"Stra\xdfe\" xmlUrl=\"https://example.com/feed\"/></body></opml>",
+			[]Entry{{Text: "Straße", FeedURL: "https://example.com/feed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if got := doc.Entries(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Entries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"empty", ""},
+		{"not xml", "subscriptions"},
+		{"unclosed", `<opml version="2.0"><body>`},
+		{"other root", `<rss version="2.0"></rss>`},
+		{"unknown charset", "<?xml version=\"1.0\" encoding=\"x-unknown\"?>\n<opml version=\"1.0\"><body/></opml>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if doc, err := Parse(strings.NewReader(tt.doc)); !errors.Is(err, ErrInvalidDocument) {
+				t.Errorf("Parse(%q) = %+v, %v, want ErrInvalidDocument", tt.doc, doc, err)
+			}
+		})
+	}
+}
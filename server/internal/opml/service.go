@@ -0,0 +1,267 @@
+package opml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrImportNotFound    = errors.New("import not found")
+	ErrImportRateLimited = errors.New("too many opml imports")
+)
+
+// KindImportOPML subscribes a user to the feeds of an uploaded OPML document
+const KindImportOPML = "opml.import"
+
+const (
+	// Each user may start importRateLimit imports per importRateWindow
+	importRateLimit  = 10
+	importRateWindow = time.Hour
+
+	// An import interrupted by a restart is resumed this many times in total
+	importMaxAttempts = 5
+)
+
+type ImportStatus string
+
+const (
+	ImportStatusPending   ImportStatus = "pending"
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusCompleted ImportStatus = "completed"
+	ImportStatusFailed    ImportStatus = "failed"
+)
+
+type ResultStatus string
+
+const (
+	ResultImported          ResultStatus = "imported"
+	ResultAlreadySubscribed ResultStatus = "already_subscribed"
+	ResultInvalid           ResultStatus = "invalid"
+	ResultFailed            ResultStatus = "failed"
+)
+
+// ImportResult is the outcome of importing a single outline
+type ImportResult struct {
+	Text    string       `json:"text"`
+	FeedURL string       `json:"feed_url"`
+	Folder  string       `json:"folder,omitempty"`
+	Status  ResultStatus `json:"status"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Import tracks the progress of an OPML import for a user
+type Import struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Status     ImportStatus
+	Total      int
+	Results    []ImportResult
+	CreatedAt  time.Time
+	FinishedAt time.Time
+}
+
+// importPayload is the payload of an opml.import job
+type importPayload struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Entries []Entry   `json:"entries"`
+}
+
+// importResult is the result an opml.import job reports, it grows as outlines
+// are imported, in document order
+type importResult struct {
+	Results []ImportResult `json:"results"`
+}
+
+// OPMLService imports and exports subscriptions as OPML. Imports run as
+// background jobs, so their status is shared by every instance and an import
+// interrupted by a restart resumes where it stopped.
+type OPMLService struct {
+	feedService *feeds.FeedService
+	jobService  *jobs.JobService
+}
+
+// NewOPMLService creates a new OPML service and registers the opml.import job
+func NewOPMLService(feedService *feeds.FeedService, jobService *jobs.JobService) *OPMLService {
+	s := &OPMLService{
+		feedService: feedService,
+		jobService:  jobService,
+	}
+
+	jobService.Handle(KindImportOPML, s.run)
+
+	return s
+}
+
+// Import enqueues subscribing the user to every feed in the document. The
+// import is returned in the pending state, poll GetImport for its progress.
+func (s *OPMLService) Import(ctx context.Context, userID uuid.UUID, doc *Document) (*Import, error) {
+	payload := importPayload{
+		UserID:  userID,
+		Entries: doc.Entries(),
+	}
+
+	job, err := s.jobService.EnqueueForUser(ctx, userID, KindImportOPML, payload, importMaxAttempts, importRateLimit, importRateWindow)
+	if err != nil {
+		if errors.Is(err, jobs.ErrRateLimited) {
+			return nil, ErrImportRateLimited
+		}
+		return nil, err
+	}
+
+	return newImport(job)
+}
+
+// GetImport returns the state of an import owned by the user
+func (s *OPMLService) GetImport(ctx context.Context, userID, importID uuid.UUID) (*Import, error) {
+	job, err := s.jobService.GetUserJob(ctx, userID, KindImportOPML, importID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+
+	return newImport(job)
+}
+
+// run is the opml.import job handler. It records the result after every
+// outline and skips the outlines an earlier attempt already recorded.
+func (s *OPMLService) run(ctx context.Context, job *models.Job) error {
+	var payload importPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var result importResult
+	if job.Result != nil {
+		if err := json.Unmarshal(job.Result, &result); err != nil {
+			return jobs.Permanent(err)
+		}
+	}
+	if len(result.Results) > len(payload.Entries) {
+		result.Results = result.Results[:len(payload.Entries)]
+	}
+
+	for _, entry := range payload.Entries[len(result.Results):] {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result.Results = append(result.Results, s.importEntry(payload.UserID, entry))
+		// Record the outline even when shutting down, so the resumed import skips it
+		if err := s.jobService.SetResult(context.WithoutCancel(ctx), job.ID, result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *OPMLService) importEntry(userID uuid.UUID, entry Entry) ImportResult {
+	result := ImportResult{
+		Text:    entry.Text,
+		FeedURL: entry.FeedURL,
+		Folder:  entry.Folder,
+	}
+
+	title := entry.Title
+	if title == "" {
+		title = entry.Text
+	}
+	if title == "" {
+		title = entry.FeedURL
+	}
+
+	// Create the feed, falling back to the existing row if someone already added it
+	feed, err := s.feedService.AddFeed(entry.FeedURL, entry.SiteURL, title, entry.Description)
+	if errors.Is(err, feeds.ErrFeedAlreadyExists) {
+		feed, err = s.feedService.GetFeedByURL(entry.FeedURL)
+	}
+	if errors.Is(err, feeds.ErrInvalidFeedURL) {
+		result.Status = ResultInvalid
+		result.Error = "missing or invalid xmlUrl"
+		return result
+	}
+	if err != nil {
+		log.Printf("opml import: failed to resolve feed %s: %v", entry.FeedURL, err)
+		result.Status = ResultFailed
+		result.Error = "could not create feed"
+		return result
+	}
+
+	subscribed, err := s.feedService.IsSubscribed(userID, feed.ID)
+	if err != nil {
+		log.Printf("opml import: failed to check subscription for %s: %v", entry.FeedURL, err)
+		result.Status = ResultFailed
+		result.Error = "could not check subscription"
+		return result
+	}
+	if subscribed {
+		result.Status = ResultAlreadySubscribed
+		return result
+	}
+
+	if err := s.feedService.SubscribeToFeed(userID, feed.ID, title, entry.Folder); err != nil {
+		log.Printf("opml import: failed to subscribe to %s: %v", entry.FeedURL, err)
+		result.Status = ResultFailed
+		result.Error = "could not subscribe to feed"
+		return result
+	}
+
+	result.Status = ResultImported
+	return result
+}
+
+// newImport builds the state of an import from its job
+func newImport(job *models.Job) (*Import, error) {
+	var payload importPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	var result importResult
+	if job.Result != nil {
+		if err := json.Unmarshal(job.Result, &result); err != nil {
+			return nil, err
+		}
+	}
+
+	imp := &Import{
+		ID:        job.ID,
+		UserID:    payload.UserID,
+		Total:     len(payload.Entries),
+		Results:   result.Results,
+		CreatedAt: job.CreatedAt,
+	}
+	if imp.Results == nil {
+		imp.Results = make([]ImportResult, 0)
+	}
+	if job.FinishedAt.Valid {
+		imp.FinishedAt = job.FinishedAt.Time
+	}
+
+	switch job.Status {
+	case models.JobStatusPending:
+		imp.Status = ImportStatusPending
+		// A retry after an interruption is still the same running import
+		if len(imp.Results) > 0 {
+			imp.Status = ImportStatusRunning
+		}
+	case models.JobStatusRunning:
+		imp.Status = ImportStatusRunning
+	case models.JobStatusDead:
+		imp.Status = ImportStatusFailed
+	default:
+		imp.Status = ImportStatusCompleted
+	}
+
+	return imp, nil
+}
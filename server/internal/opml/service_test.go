@@ -0,0 +1,102 @@
+package opml
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestNewImport(t *testing.T) {
+	userID := uuid.New()
+	finishedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	payload, err := json.Marshal(importPayload{
+		UserID: userID,
+		Entries: []Entry{
+			{Text: "A", FeedURL: "https://a.example.com/feed"},
+			{Text: "B", FeedURL: "https://b.example.com/feed"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oneResult, err := json.Marshal(importResult{Results: []ImportResult{
+		{Text: "A", FeedURL: "https://a.example.com/feed", Status: ResultImported},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		status      string
+		result      json.RawMessage
+		finished    bool
+		wantStatus  ImportStatus
+		wantResults int
+	}{
+		{"pending", models.JobStatusPending, nil, false, ImportStatusPending, 0},
+		{"running", models.JobStatusRunning, nil, false, ImportStatusRunning, 0},
+		{"running with results", models.JobStatusRunning, oneResult, false, ImportStatusRunning, 1},
+		{"retrying after interruption", models.JobStatusPending, oneResult, false, ImportStatusRunning, 1},
+		{"completed", models.JobStatusCompleted, oneResult, true, ImportStatusCompleted, 1},
+		{"dead", models.JobStatusDead, oneResult, true, ImportStatusFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{
+				ID:      uuid.New(),
+				Kind:    KindImportOPML,
+				Payload: payload,
+				Status:  tt.status,
+				Result:  tt.result,
+			}
+			if tt.finished {
+				job.FinishedAt = sql.NullTime{Time: finishedAt, Valid: true}
+			}
+
+			imp, err := newImport(job)
+			if err != nil {
+				t.Fatalf("newImport error: %v", err)
+			}
+			if imp.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", imp.Status, tt.wantStatus)
+			}
+			if imp.ID != job.ID || imp.UserID != userID || imp.Total != 2 {
+				t.Errorf("got ID %s, UserID %s, Total %d, want %s, %s, 2", imp.ID, imp.UserID, imp.Total, job.ID, userID)
+			}
+			if imp.Results == nil || len(imp.Results) != tt.wantResults {
+				t.Errorf("Results = %v, want %d results", imp.Results, tt.wantResults)
+			}
+			if tt.finished != !imp.FinishedAt.IsZero() {
+				t.Errorf("FinishedAt = %v, finished %v", imp.FinishedAt, tt.finished)
+			}
+		})
+	}
+}
+
+func TestNewImportInvalidJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload json.RawMessage
+		result  json.RawMessage
+	}{
+		{"malformed payload", json.RawMessage(`{"entries":`), nil},
+		{"malformed result", json.RawMessage(`{"entries":[]}`), json.RawMessage(`[`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{Payload: tt.payload, Status: models.JobStatusPending, Result: tt.result}
+			if imp, err := newImport(job); err == nil {
+				t.Errorf("newImport = %+v, want an error", imp)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Box encrypts secrets stored in the database with AES-256-GCM. The additional
+// data binds a ciphertext to its row or purpose, so it can't be copied elsewhere.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a Box from a base64 encoded 32 byte key. Without a
+// key one is derived from fallback, so rotating fallback makes stored secrets unreadable.
+func New(encodedKey string, fallback []byte) (*Box, error) {
+	var key []byte
+	if encodedKey != "" {
+		decoded, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("decoding encryption key: %w", err)
+		}
+		if len(decoded) != 32 {
+			return nil, errors.New("encryption key must be 32 bytes")
+		}
+		key = decoded
+	} else {
+		mac := hmac.New(sha256.New, fallback)
+		mac.Write([]byte("swayamsevak secret box"))
+		key = mac.Sum(nil)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, returning the nonce followed by the ciphertext
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a value produced by Seal with the same additional data
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	return b.aead.Open(nil, nonce, ciphertext, additionalData)
+}
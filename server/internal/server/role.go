@@ -0,0 +1,35 @@
+package server
+
+import "fmt"
+
+// Role selects which parts of the server a process runs
+type Role string
+
+const (
+	// RoleAPI serves the HTTP API only
+	RoleAPI Role = "api"
+	// RoleWorker runs the background work (feed fetch scheduler) only
+	RoleWorker Role = "worker"
+	// RoleAll runs both in a single process
+	RoleAll Role = "all"
+)
+
+// ParseRole validates a role given on the command line or in the environment
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case RoleAPI, RoleWorker, RoleAll:
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown role %q, expected api, worker or all", s)
+	}
+}
+
+// ServesAPI reports whether the role runs the HTTP API
+func (r Role) ServesAPI() bool {
+	return r == RoleAPI || r == RoleAll
+}
+
+// RunsWorker reports whether the role runs the background workers
+func (r Role) RunsWorker() bool {
+	return r == RoleWorker || r == RoleAll
+}
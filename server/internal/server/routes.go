@@ -0,0 +1,209 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/app"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/auth"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/config"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/handlers"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/metrics"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/middleware"
+	httpSwagger "github.com/swaggo/http-swagger/v2"
+)
+
+// NewRouter creates the handlers and registers every API route
+func NewRouter(app *app.App, cfg *config.Config) http.Handler {
+	// Create the handlers
+	authHandler := handlers.NewAuthHandler(app.AuthService, cfg.RefreshTokenTTL, cfg.CookieSecure, cfg.AppBaseURL)
+	userHandler := handlers.NewUserHandler(app.UserRepo, app.AuthService)
+	feedHandler := handlers.NewFeedHandler(app.FeedService)
+	opmlHandler := handlers.NewOPMLHandler(app.OPMLService)
+	refreshHandler := handlers.NewRefreshHandler(app.RefreshService)
+	jobHandler := handlers.NewJobHandler(app.JobService)
+	streamHandler := handlers.NewStreamHandler(app.StreamService, app.AuthService)
+	sessionHandler := handlers.NewSessionHandler(app.AuthService)
+	mfaHandler := handlers.NewMFAHandler(app.AuthService)
+	apiTokenHandler := handlers.NewAPITokenHandler(app.AuthService)
+	adminUserHandler := handlers.NewAdminUserHandler(app.AuthService)
+
+	mux := http.NewServeMux()
+
+	// Swagger documentation route
+	mux.HandleFunc("GET /swagger/",
+		httpSwagger.Handler(httpSwagger.URL("/swagger/doc.json")),
+	)
+
+	// Create the routes
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello, World!"))
+	})
+
+	// Public Routes
+
+	// Auth Routes
+	mux.HandleFunc("POST /api/auth/register", authHandler.Register)
+	mux.HandleFunc("POST /api/auth/login", authHandler.Login)
+	mux.HandleFunc("POST /api/auth/login/mfa", authHandler.LoginMFA)
+	mux.HandleFunc("POST /api/auth/refresh", authHandler.RefreshToken)
+	mux.HandleFunc("POST /api/auth/logout", authHandler.Logout)
+	mux.HandleFunc("POST /api/auth/verify-email", authHandler.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/password/forgot", authHandler.ForgotPassword)
+	mux.HandleFunc("POST /api/auth/password/reset", authHandler.ResetPassword)
+	mux.HandleFunc("GET /api/auth/oidc/login", authHandler.OIDCLogin)
+	mux.HandleFunc("GET /api/auth/oidc/callback", authHandler.OIDCCallback)
+
+	protectedResendVerification := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(authHandler.ResendVerification))
+	mux.Handle("POST /api/auth/verify-email/resend", protectedResendVerification)
+
+	// Routes that add feeds or subscriptions need a verified email, personal
+	// access tokens need the subscriptions:write scope
+	requireVerified := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(app.AuthService, auth.ScopeSubscriptionsWrite)(middleware.RequireVerified(app.UserRepo)(h))
+	}
+
+	// Routes without a scope are off limits to personal access tokens
+	readArticles := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(app.AuthService, auth.ScopeArticlesRead)(h)
+	}
+	writeArticles := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(app.AuthService, auth.ScopeArticlesWrite)(h)
+	}
+	manageSubscriptions := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(app.AuthService, auth.ScopeSubscriptionsWrite)(h)
+	}
+
+	// User Routes
+	protectedProfile := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(userHandler.Profile))
+	mux.Handle("GET /api/profile", protectedProfile)
+
+	protectedUpdateProfile := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(userHandler.UpdateProfile))
+	mux.Handle("PATCH /api/profile", protectedUpdateProfile)
+
+	protectedDeleteAccount := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(userHandler.DeleteAccount))
+	mux.Handle("DELETE /api/profile", protectedDeleteAccount)
+
+	protectedChangePassword := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(userHandler.ChangePassword))
+	mux.Handle("POST /api/profile/password", protectedChangePassword)
+
+	protectedChangeEmail := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(userHandler.ChangeEmail))
+	mux.Handle("PUT /api/profile/email", protectedChangeEmail)
+
+	// Admin routes, with ADMIN_REQUIRE_MFA only for admins with two-factor authentication
+	requireAdmin := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(app.AuthService, auth.ScopeAdmin)(middleware.RequireAdmin(app.UserRepo, cfg.AdminRequireMFA)(h))
+	}
+
+	// Feed Routes, global feeds are managed by admins and users subscribe by URL
+	mux.Handle("POST /api/feed", requireAdmin(feedHandler.AddFeedHandler))
+
+	mux.Handle("GET /api/feeds", requireAdmin(feedHandler.GetAllFeedsHandler))
+
+	mux.Handle("GET /api/subscriptions", readArticles(feedHandler.ListSubscriptionsHandler))
+
+	mux.Handle("POST /api/feed/subscribe", requireVerified(feedHandler.SubscribeToFeedHandler))
+
+	mux.Handle("POST /api/subscriptions", requireVerified(feedHandler.SubscribeByURLHandler))
+
+	mux.Handle("GET /api/feed/articles", readArticles(feedHandler.GetUserArticlesHandler))
+
+	mux.Handle("PATCH /api/articles/{id}/state", writeArticles(feedHandler.SetArticleStateHandler))
+
+	// Refresh Routes
+	mux.Handle("POST /api/feeds/{id}/refresh", requireVerified(refreshHandler.RefreshFeedHandler))
+
+	mux.Handle("POST /api/subscriptions/refresh", requireVerified(refreshHandler.RefreshSubscriptionsHandler))
+
+	mux.Handle("GET /api/refresh/{id}", manageSubscriptions(refreshHandler.RefreshStatusHandler))
+
+	// OPML Routes
+	mux.Handle("POST /api/opml/import", requireVerified(opmlHandler.ImportHandler))
+
+	mux.Handle("GET /api/opml/import/{id}", manageSubscriptions(opmlHandler.ImportStatusHandler))
+
+	mux.Handle("GET /api/opml/export", readArticles(opmlHandler.ExportHandler))
+
+	// Session Routes
+	protectedListSessions := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(sessionHandler.ListSessionsHandler))
+	mux.Handle("GET /api/sessions", protectedListSessions)
+
+	protectedRevokeSession := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(sessionHandler.RevokeSessionHandler))
+	mux.Handle("DELETE /api/sessions/{id}", protectedRevokeSession)
+
+	protectedRevokeOtherSessions := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(sessionHandler.RevokeOtherSessionsHandler))
+	mux.Handle("POST /api/sessions/revoke-others", protectedRevokeOtherSessions)
+
+	// MFA Routes
+	protectedMFAStatus := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(mfaHandler.StatusHandler))
+	mux.Handle("GET /api/mfa", protectedMFAStatus)
+
+	protectedEnrollTOTP := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(mfaHandler.EnrollTOTPHandler))
+	mux.Handle("POST /api/mfa/totp/enroll", protectedEnrollTOTP)
+
+	protectedConfirmTOTP := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(mfaHandler.ConfirmTOTPHandler))
+	mux.Handle("POST /api/mfa/totp/confirm", protectedConfirmTOTP)
+
+	protectedDisableTOTP := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(mfaHandler.DisableTOTPHandler))
+	mux.Handle("POST /api/mfa/totp/disable", protectedDisableTOTP)
+
+	protectedRegenerateRecoveryCodes := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(mfaHandler.RegenerateRecoveryCodesHandler))
+	mux.Handle("POST /api/mfa/recovery-codes", protectedRegenerateRecoveryCodes)
+
+	// Personal Access Token Routes
+	protectedCreateAPIToken := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(apiTokenHandler.CreateAPITokenHandler))
+	mux.Handle("POST /api/tokens", protectedCreateAPIToken)
+
+	protectedListAPITokens := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(apiTokenHandler.ListAPITokensHandler))
+	mux.Handle("GET /api/tokens", protectedListAPITokens)
+
+	protectedRevokeAPIToken := middleware.AuthMiddleware(app.AuthService)(http.HandlerFunc(apiTokenHandler.RevokeAPITokenHandler))
+	mux.Handle("DELETE /api/tokens/{id}", protectedRevokeAPIToken)
+
+	// Stream Routes
+	mux.Handle("POST /api/stream/ticket", readArticles(streamHandler.StreamTicketHandler))
+
+	protectedStream := middleware.StreamAuthMiddleware(app.AuthService, auth.ScopeArticlesRead)(http.HandlerFunc(streamHandler.StreamArticlesHandler))
+	mux.Handle("GET /api/stream", protectedStream)
+
+	// Admin Routes
+	mux.Handle("GET /api/admin/jobs", requireAdmin(jobHandler.ListJobsHandler))
+	mux.Handle("GET /api/admin/jobs/{id}", requireAdmin(jobHandler.GetJobHandler))
+	mux.Handle("POST /api/admin/jobs/{id}/retry", requireAdmin(jobHandler.RetryJobHandler))
+	mux.Handle("GET /api/admin/feeds/{id}/retention", requireAdmin(feedHandler.GetFeedRetentionHandler))
+	mux.Handle("PUT /api/admin/feeds/{id}/retention", requireAdmin(feedHandler.SetFeedRetentionHandler))
+	mux.Handle("DELETE /api/admin/feeds/{id}", requireAdmin(feedHandler.DeleteFeedHandler))
+	mux.Handle("GET /api/admin/users", requireAdmin(adminUserHandler.ListUsersHandler))
+	mux.Handle("GET /api/admin/users/{id}", requireAdmin(adminUserHandler.GetUserHandler))
+	mux.Handle("PUT /api/admin/users/{id}/role", requireAdmin(adminUserHandler.SetUserRoleHandler))
+	mux.Handle("DELETE /api/admin/users/{id}", requireAdmin(adminUserHandler.DeleteUserHandler))
+
+	// Apply CORS, record request metrics and take the client address from trusted proxies
+	return middleware.RealIP(cfg.TrustedProxies)(metrics.Instrument(enableCORS(mux)))
+}
+
+// enableCORS sets the necessary headers to allow React frontend communication
+func enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Set headers
+		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173") // Adjust port for your React app
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+		// Handle preflight requests
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newMetricsRouter serves /metrics on the separate admin listener
+func newMetricsRouter() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return mux
+}
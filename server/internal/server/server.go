@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/app"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/config"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/database"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/feeds"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/jobs"
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/metrics"
+)
+
+// Run wires up the App and runs the parts selected by role until SIGINT/SIGTERM,
+// then shuts everything down gracefully. When the API or metrics listener fails
+// everything is shut down as well and the listener's error is returned.
+func Run(cfg *config.Config, role Role) error {
+	// Connect to the database
+	db, err := database.Connect(cfg.DBDSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the database: %w", err)
+	}
+
+	// Create the App
+	app, err := app.NewApp(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Key feeds from before url_key existed, duplicates would slip past AddFeed otherwise
+	report, err := app.FeedService.BackfillURLKeys(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to backfill feed url keys: %w", err)
+	}
+	if report.KeysUpdated > 0 || len(report.Groups) > 0 {
+		log.Printf("Backfilled %d feed url keys and merged %d groups of duplicate feeds", report.KeysUpdated, len(report.Groups))
+	}
+	for _, invalid := range report.InvalidURLs {
+		log.Printf("Feed %q has an invalid url and no url key", invalid)
+	}
+
+	// Bootstrap admins from ADMIN_EMAILS
+	if promoted, err := app.AuthService.PromoteAdmins(context.Background(), cfg.AdminEmails); err != nil {
+		log.Printf("Failed to promote admins: %v", err)
+	} else if promoted > 0 {
+		log.Printf("Promoted %d users from ADMIN_EMAILS to admin", promoted)
+	}
+
+	// Export pool and feed stats on every scrape
+	metrics.RegisterDB(db)
+	metrics.RegisterFeedStats(app.FeedRepo, cfg.FetchInterval)
+
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background work runs on its own context so it outlives the HTTP drain
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	var workers sync.WaitGroup
+	if role.RunsWorker() {
+		startWorkers(workerCtx, &workers, app, cfg)
+	}
+
+	// Listener failures such as a port in use end the process with an error
+	var srv *http.Server
+	serveErr := make(chan error, 2)
+	if role.ServesAPI() {
+		srv = &http.Server{
+			Addr:         ":" + cfg.Port,
+			Handler:      NewRouter(app, cfg),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		// Article streams are long-lived, end them when the server starts draining
+		srv.RegisterOnShutdown(app.Broker.Close)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			app.Broker.Start(workerCtx)
+		}()
+
+		fmt.Printf("Server running on \x1b[91mhttp://localhost:%s\x1b[0m\n", cfg.Port)
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("server error: %w", err)
+			}
+		}()
+	}
+
+	// Metrics get their own listener so they stay off the public port
+	var metricsSrv *http.Server
+	if cfg.MetricsAddr != "off" {
+		metricsSrv = &http.Server{
+			Addr:        cfg.MetricsAddr,
+			Handler:     newMetricsRouter(),
+			ReadTimeout: 5 * time.Second,
+			IdleTimeout: 60 * time.Second,
+		}
+
+		log.Printf("Serving metrics on %s/metrics", cfg.MetricsAddr)
+		go func() {
+			if err := metricsSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("metrics server error: %w", err)
+			}
+		}()
+	}
+
+	log.Printf("Running with role %q", role)
+
+	var runErr error
+	select {
+	case runErr = <-serveErr:
+		log.Printf("%v, shutting down", runErr)
+	case <-ctx.Done():
+		log.Println("Shutdown signal received, draining in-flight work")
+	}
+
+	// A second signal kills the process immediately
+	stop()
+
+	shutdown(srv, metricsSrv, cancelWorkers, &workers, app, db, cfg.ShutdownTimeout)
+	return runErr
+}
+
+// startWorkers starts every background component of the worker role
+func startWorkers(ctx context.Context, wg *sync.WaitGroup, app *app.App, cfg *config.Config) {
+	// Start the worker to scrape feeds
+	feedWorker := feeds.NewWorker(app.FeedService, feeds.WorkerConfig{
+		Interval:   cfg.FetchInterval,
+		PoolSize:   cfg.FetchPoolSize,
+		QueueDepth: cfg.FetchQueueDepth,
+	})
+
+	// Start the worker to run background jobs
+	jobWorker := jobs.NewWorker(app.JobService, jobs.WorkerConfig{
+		Concurrency:  cfg.JobConcurrency,
+		PollInterval: cfg.JobPollInterval,
+	})
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		feedWorker.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		jobWorker.Start(ctx)
+	}()
+}
+
+// shutdown stops the process in order: the HTTP server drains its requests,
+// then the background workers are cancelled and awaited, and finally the
+// database pool is closed. The metrics server goes last so the drain can still
+// be observed. Everything shares a single drain timeout.
+func shutdown(srv, metricsSrv *http.Server, cancelWorkers context.CancelFunc, workers *sync.WaitGroup, app *app.App, db *sql.DB, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("HTTP server did not shut down cleanly: %v", err)
+		}
+	}
+
+	cancelWorkers()
+
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the background workers to stop")
+	}
+
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server did not shut down cleanly: %v", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		log.Printf("Failed to close the database: %v", err)
+	}
+
+	log.Println("Server stopped")
+}
@@ -0,0 +1,271 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Reconnect delays of the LISTEN connection
+const (
+	minListenBackoff = time.Second
+	maxListenBackoff = 30 * time.Second
+)
+
+// Broker listens for new article and read state notifications on a dedicated
+// Postgres connection and wakes the local streams they concern. Any instance
+// that inserts articles or marks them read notifies all instances, so streams
+// work regardless of which process made the change.
+type Broker struct {
+	dsn string
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription collects the feeds that received new articles, and the feeds
+// whose unread count the user changed, since the stream last looked. Wake is
+// signalled whenever either set grows.
+type Subscription struct {
+	broker *Broker
+	userID uuid.UUID
+
+	wake chan struct{}
+	done chan struct{}
+
+	mu      sync.Mutex
+	feedIDs map[uuid.UUID]struct{}
+	// all is set when any feed may have new articles, e.g. after the LISTEN connection was lost
+	all bool
+	// recount holds the feeds in which the user marked articles read or unread
+	recount map[uuid.UUID]struct{}
+}
+
+// NewBroker creates a broker that listens on the database at dsn
+func NewBroker(dsn string) *Broker {
+	return &Broker{
+		dsn:  dsn,
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Start listens for notifications until ctx is cancelled, reconnecting with
+// backoff whenever the connection is lost
+func (b *Broker) Start(ctx context.Context) {
+	backoff := minListenBackoff
+
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Notifications may have been missed while disconnected
+		b.wakeAll()
+
+		if time.Since(start) > maxListenBackoff {
+			backoff = minListenBackoff
+		}
+		log.Printf("stream: lost the notification connection, reconnecting in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxListenBackoff)
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	for _, channel := range []string{models.ArticlesInsertedChannel, models.ArticleStatesChangedChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		if notification.Channel == models.ArticleStatesChangedChannel {
+			var payload models.ArticleStatesChanged
+			if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+				log.Printf("stream: ignoring malformed notification %q: %v", notification.Payload, err)
+				continue
+			}
+
+			b.notifyStates(payload.UserID, payload.FeedIDs)
+			continue
+		}
+
+		var payload models.ArticlesInserted
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			log.Printf("stream: ignoring malformed notification %q: %v", notification.Payload, err)
+			continue
+		}
+
+		if len(payload.FeedIDs) == 0 {
+			b.wakeAll()
+			continue
+		}
+		b.notify(payload.FeedIDs)
+	}
+}
+
+// Subscribe registers a stream of the user, the subscription must be closed when the stream ends
+func (b *Broker) Subscribe(userID uuid.UUID) *Subscription {
+	sub := &Subscription{
+		broker:  b,
+		userID:  userID,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		feedIDs: make(map[uuid.UUID]struct{}),
+		recount: make(map[uuid.UUID]struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(sub.done)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Close ends every subscription so open streams return, e.g. on shutdown.
+// Subscriptions made afterwards are closed right away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		close(sub.done)
+		delete(b.subs, sub)
+	}
+}
+
+func (b *Broker) notify(feedIDs []uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		sub.add(feedIDs, false)
+	}
+}
+
+// notifyStates wakes the user's streams to recount the unread articles of feedIDs
+func (b *Broker) notifyStates(userID uuid.UUID, feedIDs []uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.userID == userID {
+			sub.addRecount(feedIDs)
+		}
+	}
+}
+
+func (b *Broker) wakeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		sub.add(nil, true)
+	}
+}
+
+func (s *Subscription) add(feedIDs []uuid.UUID, all bool) {
+	s.mu.Lock()
+	for _, id := range feedIDs {
+		s.feedIDs[id] = struct{}{}
+	}
+	s.all = s.all || all
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) addRecount(feedIDs []uuid.UUID) {
+	s.mu.Lock()
+	for _, id := range feedIDs {
+		s.recount[id] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wake is signalled when feeds received new articles or unread counts changed,
+// call Pending to find out which
+func (s *Subscription) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// Done is closed when the broker shuts down
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Pending returns and resets the feeds that received new articles and the feeds
+// whose unread count the user changed. all reports that any feed may have new articles.
+func (s *Subscription) Pending() (feedIDs []uuid.UUID, all bool, recount []uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feedIDs = make([]uuid.UUID, 0, len(s.feedIDs))
+	for id := range s.feedIDs {
+		feedIDs = append(feedIDs, id)
+	}
+	clear(s.feedIDs)
+
+	recount = make([]uuid.UUID, 0, len(s.recount))
+	for id := range s.recount {
+		recount = append(recount, id)
+	}
+	clear(s.recount)
+
+	all = s.all
+	s.all = false
+
+	return feedIDs, all, recount
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	b := s.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.done)
+	}
+}
@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSubscriptionPending(t *testing.T) {
+	user, otherUser := uuid.New(), uuid.New()
+	feedA, feedB := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		notify      func(b *Broker)
+		wantWake    bool
+		wantFeeds   []uuid.UUID
+		wantAll     bool
+		wantRecount []uuid.UUID
+	}{
+		{
+			"nothing",
+			func(b *Broker) {},
+			false, nil, false, nil,
+		},
+		{
+			"new articles",
+			func(b *Broker) { b.notify([]uuid.UUID{feedA, feedB}) },
+			true, []uuid.UUID{feedA, feedB}, false, nil,
+		},
+		{
+			"repeated feeds",
+			func(b *Broker) {
+				b.notify([]uuid.UUID{feedA})
+				b.notify([]uuid.UUID{feedA})
+			},
+			true, []uuid.UUID{feedA}, false, nil,
+		},
+		{
+			"any feed",
+			func(b *Broker) { b.wakeAll() },
+			true, nil, true, nil,
+		},
+		{
+			"reconnected",
+			func(b *Broker) {
+				b.notify([]uuid.UUID{feedA})
+				b.wakeAll()
+			},
+			true, []uuid.UUID{feedA}, true, nil,
+		},
+		{
+			"read state changed",
+			func(b *Broker) { b.notifyStates(user, []uuid.UUID{feedB}) },
+			true, nil, false, []uuid.UUID{feedB},
+		},
+		{
+			"read state of another user",
+			func(b *Broker) { b.notifyStates(otherUser, []uuid.UUID{feedB}) },
+			false, nil, false, nil,
+		},
+		{
+			"new articles and read state",
+			func(b *Broker) {
+				b.notify([]uuid.UUID{feedA})
+				b.notifyStates(user, []uuid.UUID{feedA, feedB})
+			},
+			true, []uuid.UUID{feedA}, false, []uuid.UUID{feedA, feedB},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker("")
+			sub := b.Subscribe(user)
+			defer sub.Close()
+
+			tt.notify(b)
+
+			woke := false
+			select {
+			case <-sub.Wake():
+				woke = true
+			default:
+			}
+			if woke != tt.wantWake {
+				t.Errorf("woke = %v, want %v", woke, tt.wantWake)
+			}
+
+			feedIDs, all, recount := sub.Pending()
+			if !sameFeeds(feedIDs, tt.wantFeeds) || all != tt.wantAll || !sameFeeds(recount, tt.wantRecount) {
+				t.Errorf("Pending() = %v, %v, %v, want %v, %v, %v", feedIDs, all, recount, tt.wantFeeds, tt.wantAll, tt.wantRecount)
+			}
+
+			// Pending resets what it returned
+			feedIDs, all, recount = sub.Pending()
+			if len(feedIDs) != 0 || all || len(recount) != 0 {
+				t.Errorf("second Pending() = %v, %v, %v, want nothing", feedIDs, all, recount)
+			}
+		})
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker("")
+	sub := b.Subscribe(uuid.New())
+	closed := b.Subscribe(uuid.New())
+	closed.Close()
+
+	b.Close()
+
+	for name, s := range map[string]*Subscription{"open": sub, "closed": closed, "late": b.Subscribe(uuid.New())} {
+		select {
+		case <-s.Done():
+		default:
+			t.Errorf("%s subscription isn't done after Close", name)
+		}
+	}
+
+	// Closing a subscription of a closed broker is a no-op
+	sub.Close()
+}
+
+func sameFeeds(got, want []uuid.UUID) bool {
+	got, want = slices.Clone(got), slices.Clone(want)
+	slices.SortFunc(got, uuidCompare)
+	slices.SortFunc(want, uuidCompare)
+	return slices.Equal(got, want)
+}
+
+func uuidCompare(a, b uuid.UUID) int {
+	return slices.Compare(a[:], b[:])
+}
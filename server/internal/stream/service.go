@@ -0,0 +1,174 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/Harshitttttttt/Swayamsevak/server/internal/models"
+	"github.com/google/uuid"
+)
+
+// Articles are loaded this many at a time when catching a stream up
+const articleBatchSize = 100
+
+// A stream rereads the user's subscriptions at most this often
+const subscriptionsTTL = time.Minute
+
+// StreamService provides the data pushed over article streams
+type StreamService struct {
+	articleRepo          *models.ArticleRepository
+	feedSubscriptionRepo *models.FeedSubscriptionRepository
+	broker               *Broker
+}
+
+// NewStreamService creates a new stream service
+func NewStreamService(articleRepo *models.ArticleRepository, feedSubscriptionRepo *models.FeedSubscriptionRepository, broker *Broker) *StreamService {
+	return &StreamService{
+		articleRepo:          articleRepo,
+		feedSubscriptionRepo: feedSubscriptionRepo,
+		broker:               broker,
+	}
+}
+
+// Stream follows the new articles of a single user's subscriptions. It is not
+// safe for concurrent use.
+type Stream struct {
+	service *StreamService
+	sub     *Subscription
+	userID  uuid.UUID
+
+	// cursor is the seq of the last article delivered
+	cursor int64
+
+	feedIDs    map[uuid.UUID]struct{}
+	feedsUntil time.Time
+}
+
+// Open starts following new articles for the user. Articles stored after the
+// one at lastSeq are delivered first; with lastSeq < 0 only articles stored
+// from now on are delivered.
+func (s *StreamService) Open(ctx context.Context, userID uuid.UUID, lastSeq int64) (*Stream, error) {
+	// Subscribe before reading the cursor so no notification falls in between
+	sub := s.broker.Subscribe(userID)
+
+	if lastSeq < 0 {
+		latest, err := s.articleRepo.GetLatestArticleSeq(ctx)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+		lastSeq = latest
+	} else {
+		// Resuming, whatever was stored meanwhile must be delivered
+		sub.add(nil, true)
+	}
+
+	return &Stream{
+		service: s,
+		sub:     sub,
+		userID:  userID,
+		cursor:  lastSeq,
+	}, nil
+}
+
+// Wake is signalled when new articles may be available, call Next to read them
+func (st *Stream) Wake() <-chan struct{} {
+	return st.sub.Wake()
+}
+
+// Done is closed when the server shuts down
+func (st *Stream) Done() <-chan struct{} {
+	return st.sub.Done()
+}
+
+// Close stops following new articles
+func (st *Stream) Close() {
+	st.sub.Close()
+}
+
+// Next returns the articles stored since the last call, oldest first, and the
+// unread counts of the feeds they belong to or in which the user marked articles
+// read or unread, e.g. on another device. It returns nothing when the
+// notifications were about feeds the user doesn't follow.
+func (st *Stream) Next(ctx context.Context) ([]*models.Article, map[uuid.UUID]int64, error) {
+	feedIDs, all, recount := st.sub.Pending()
+
+	relevant := all
+	if !relevant && len(feedIDs) > 0 {
+		var err error
+		relevant, err = st.follows(feedIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	articles := make([]*models.Article, 0)
+	for relevant {
+		batch, err := st.service.articleRepo.GetUserArticlesAfter(ctx, st.userID, st.cursor, articleBatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		articles = append(articles, batch...)
+		st.cursor = batch[len(batch)-1].Seq
+
+		if len(batch) < articleBatchSize {
+			break
+		}
+	}
+
+	changed := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]struct{})
+	for _, article := range articles {
+		if _, ok := seen[article.FeedID]; !ok {
+			seen[article.FeedID] = struct{}{}
+			changed = append(changed, article.FeedID)
+		}
+	}
+	for _, feedID := range recount {
+		if _, ok := seen[feedID]; !ok {
+			seen[feedID] = struct{}{}
+			changed = append(changed, feedID)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil, nil, nil
+	}
+
+	unread, err := st.service.articleRepo.GetUnreadCounts(ctx, st.userID, changed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articles, unread, nil
+}
+
+// follows reports whether the user subscribes to any of the feeds. The
+// subscriptions are cached briefly, a feed subscribed to in the meantime is
+// caught up on the next notification because articles are read by cursor.
+func (st *Stream) follows(feedIDs []uuid.UUID) (bool, error) {
+	if time.Now().After(st.feedsUntil) {
+		subscriptions, err := st.service.feedSubscriptionRepo.GetSubscriptionsByUser(st.userID)
+		if err != nil {
+			return false, err
+		}
+
+		st.feedIDs = make(map[uuid.UUID]struct{}, len(subscriptions))
+		for _, subscription := range subscriptions {
+			st.feedIDs[subscription.FeedID] = struct{}{}
+		}
+		st.feedsUntil = time.Now().Add(subscriptionsTTL)
+	}
+
+	for _, id := range feedIDs {
+		if _, ok := st.feedIDs[id]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
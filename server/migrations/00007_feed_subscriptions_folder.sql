@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_subscriptions
+  ADD COLUMN folder TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feed_subscriptions
+  DROP COLUMN folder;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- url_key identifies a feed independently of scheme, "www." and trailing slashes.
+-- Existing rows start out NULL, the server backfills their keys on startup and
+-- merges feeds that turn out to be duplicates (`go run ./cmd/admin merge-feeds`
+-- does the same by hand).
+ALTER TABLE feeds
+  ADD COLUMN url_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS feeds_url_key_idx ON feeds (url_key);
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_url_key_idx;
+
+ALTER TABLE feeds
+  DROP COLUMN url_key;
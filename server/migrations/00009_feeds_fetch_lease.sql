@@ -0,0 +1,15 @@
+-- +goose Up
+-- A worker leases a feed while fetching it. Leases that are not released before
+-- lease_expires_at (e.g. the worker crashed) can be claimed by any other worker.
+ALTER TABLE feeds
+  ADD COLUMN leased_by TEXT,
+  ADD COLUMN lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS feeds_last_fetched_at_idx ON feeds (last_fetched_at NULLS FIRST);
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_last_fetched_at_idx;
+
+ALTER TABLE feeds
+  DROP COLUMN lease_expires_at,
+  DROP COLUMN leased_by;
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE jobs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind TEXT NOT NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  -- pending, running, completed or dead (retries exhausted)
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 5,
+  run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  unique_key TEXT,
+  last_error TEXT,
+  locked_by TEXT,
+  locked_until TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP
+);
+
+-- Only one unfinished job may hold a given uniqueness key
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_unique_key_idx ON jobs (unique_key)
+  WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS jobs_pending_run_at_idx ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS jobs_status_kind_idx ON jobs (status, kind);
+
+-- +goose Down
+DROP INDEX IF EXISTS jobs_status_kind_idx;
+DROP INDEX IF EXISTS jobs_pending_run_at_idx;
+DROP INDEX IF EXISTS jobs_unique_key_idx;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Per-feed overrides of the global article retention policy. NULL falls back to
+-- the global default, 0 keeps articles forever.
+ALTER TABLE feeds
+  ADD COLUMN retention_days INTEGER CHECK (retention_days >= 0),
+  ADD COLUMN retention_max_articles INTEGER CHECK (retention_max_articles >= 0);
+
+-- Per-user state of an article. Articles a user starred, saved or annotated are
+-- never pruned.
+CREATE TABLE IF NOT EXISTS article_states (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  article_id UUID NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+  starred BOOLEAN NOT NULL DEFAULT FALSE,
+  saved BOOLEAN NOT NULL DEFAULT FALSE,
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (user_id, article_id)
+);
+
+CREATE INDEX IF NOT EXISTS article_states_kept_idx ON article_states (article_id)
+WHERE starred OR saved OR note <> '';
+
+CREATE INDEX IF NOT EXISTS articles_created_at_idx ON articles (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS articles_created_at_idx;
+DROP INDEX IF EXISTS article_states_kept_idx;
+DROP TABLE IF EXISTS article_states;
+
+ALTER TABLE feeds
+  DROP COLUMN retention_max_articles,
+  DROP COLUMN retention_days;
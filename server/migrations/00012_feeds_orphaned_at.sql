@@ -0,0 +1,15 @@
+-- +goose Up
+-- Set by the orphan feed collector when a feed has no subscribers left, cleared
+-- once someone subscribes again. Feeds orphaned for longer than the grace
+-- period are deleted together with their articles.
+ALTER TABLE feeds
+  ADD COLUMN orphaned_at TIMESTAMP;
+
+-- The (user_id, feed_id) unique index can't answer "does this feed have subscribers"
+CREATE INDEX IF NOT EXISTS feed_subscriptions_feed_id_idx ON feed_subscriptions (feed_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS feed_subscriptions_feed_id_idx;
+
+ALTER TABLE feeds
+  DROP COLUMN orphaned_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Monotonic position of each article, used as the SSE event id so clients can
+-- resume a stream with Last-Event-ID
+ALTER TABLE articles
+  ADD COLUMN seq BIGINT GENERATED ALWAYS AS IDENTITY;
+
+CREATE UNIQUE INDEX IF NOT EXISTS articles_seq_idx ON articles (seq);
+
+-- Articles without a read state row, or with read = FALSE, count as unread
+ALTER TABLE article_states
+  ADD COLUMN read BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE article_states
+  DROP COLUMN read;
+
+DROP INDEX IF EXISTS articles_seq_idx;
+
+ALTER TABLE articles
+  DROP COLUMN seq;
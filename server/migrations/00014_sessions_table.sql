@@ -0,0 +1,41 @@
+-- +goose Up
+-- A session is one signed-in device. Every refresh token belongs to a session
+-- and rotating a token keeps it in the same session, so revoking a session
+-- signs that device out without touching the others.
+CREATE TABLE IF NOT EXISTS sessions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device_name TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+ALTER TABLE refresh_tokens
+  ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON refresh_tokens (session_id);
+
+-- Keep existing logins working: every live refresh token becomes its own session
+INSERT INTO sessions (id, user_id, created_at, last_used_at, expires_at)
+SELECT id, user_id, created_at, updated_at, expires_at
+FROM refresh_tokens
+WHERE NOT revoked AND expires_at > now();
+
+UPDATE refresh_tokens
+SET session_id = id
+WHERE NOT revoked AND expires_at > now();
+
+-- +goose Down
+DROP INDEX IF EXISTS refresh_tokens_session_id_idx;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN session_id;
+
+DROP INDEX IF EXISTS sessions_user_id_idx;
+DROP TABLE IF EXISTS sessions;
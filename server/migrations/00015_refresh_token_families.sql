@@ -0,0 +1,41 @@
+-- +goose Up
+-- A token family is the chain of refresh tokens issued by rotating the token
+-- handed out at sign-in, named after that first token. A token that was already
+-- rotated must never be presented again, so when one is the whole family is
+-- revoked and a security event is recorded.
+ALTER TABLE refresh_tokens
+  ADD COLUMN family_id UUID,
+  ADD COLUMN rotated_at TIMESTAMP;
+
+-- Every session holds exactly one chain, older tokens start their own family
+UPDATE refresh_tokens
+SET family_id = COALESCE(session_id, id);
+
+ALTER TABLE refresh_tokens
+  ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_expires_at_idx ON refresh_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS security_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  session_id UUID,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS security_events_user_id_idx ON security_events (user_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS security_events_user_id_idx;
+DROP TABLE IF EXISTS security_events;
+
+DROP INDEX IF EXISTS refresh_tokens_expires_at_idx;
+DROP INDEX IF EXISTS refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN rotated_at,
+  DROP COLUMN family_id;
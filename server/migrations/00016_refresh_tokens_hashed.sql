@@ -0,0 +1,39 @@
+-- +goose Up
+-- Refresh tokens are bearer credentials, so only their SHA-256 digest is kept.
+-- Clients still hold the raw token, existing rows are hashed in place and keep
+-- working since lookups hash the presented token the same way.
+ALTER TABLE refresh_tokens
+  ADD COLUMN token_hash BYTEA;
+
+UPDATE refresh_tokens
+SET token_hash = sha256(convert_to(token, 'UTF8'));
+
+ALTER TABLE refresh_tokens
+  ALTER COLUMN token_hash SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS refresh_tokens_token_hash_key ON refresh_tokens (token_hash);
+
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN token;
+
+-- +goose Down
+-- The raw tokens are gone, so every refresh token is revoked and clients have
+-- to sign in again. The hex digest only fills the NOT NULL UNIQUE column.
+ALTER TABLE refresh_tokens
+  ADD COLUMN token VARCHAR(255);
+
+UPDATE refresh_tokens
+SET token = encode(token_hash, 'hex'), revoked = true;
+
+ALTER TABLE refresh_tokens
+  ALTER COLUMN token SET NOT NULL,
+  ADD CONSTRAINT refresh_tokens_token_key UNIQUE (token);
+
+CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
+
+DROP INDEX IF EXISTS refresh_tokens_token_hash_key;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN token_hash;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE users
+  ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed keep working
+UPDATE users
+SET email_verified_at = created_at;
+
+-- Single-use tokens emailed to users. Only a digest is stored and the token is
+-- bound to the address it was sent to, so it stops working if the email changes.
+CREATE TABLE IF NOT EXISTS user_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL,
+  token_hash BYTEA NOT NULL UNIQUE,
+  email TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_id_idx ON user_tokens (user_id, purpose, created_at DESC);
+CREATE INDEX IF NOT EXISTS user_tokens_expires_at_idx ON user_tokens (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS user_tokens_expires_at_idx;
+DROP INDEX IF EXISTS user_tokens_user_id_idx;
+DROP TABLE IF EXISTS user_tokens;
+
+ALTER TABLE users
+  DROP COLUMN email_verified_at;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- TOTP two-factor authentication. The secret is encrypted by the application
+-- and only counts once confirmed with a first code. totp_last_step is the time
+-- step of the last accepted code, so a code can't be used twice.
+ALTER TABLE users
+  ADD COLUMN totp_secret BYTEA,
+  ADD COLUMN totp_enabled_at TIMESTAMP,
+  ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- One-time recovery codes for when the authenticator is lost, stored as digests
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash BYTEA NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  used_at TIMESTAMP,
+  UNIQUE (user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+ALTER TABLE users
+  DROP COLUMN totp_last_step,
+  DROP COLUMN totp_enabled_at,
+  DROP COLUMN totp_secret;
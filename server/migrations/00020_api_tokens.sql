@@ -0,0 +1,21 @@
+-- +goose Up
+-- Personal access tokens for scripts. Only a SHA-256 digest of the token is
+-- stored, token_prefix keeps its first characters so users can tell tokens apart.
+CREATE TABLE IF NOT EXISTS api_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  token_hash BYTEA NOT NULL UNIQUE,
+  token_prefix TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP,
+  last_used_ip TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_expires_at ON api_tokens(expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS api_tokens;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Roles decide what a user may do, admins operate the instance. The first admin
+-- comes from ADMIN_EMAILS or the admin command, existing users start as readers.
+ALTER TABLE users
+  ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+CREATE INDEX IF NOT EXISTS idx_users_admins ON users(id) WHERE role = 'admin';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_admins;
+
+ALTER TABLE users
+  DROP COLUMN role;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Jobs a user requested, such as on-demand refreshes, record who asked for them
+-- so status can be polled from any instance. result holds what the job reports
+-- back, it is updated while the job runs to show progress.
+ALTER TABLE jobs
+  ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  ADD COLUMN result JSONB;
+
+CREATE INDEX IF NOT EXISTS jobs_user_kind_created_idx ON jobs (user_id, kind, created_at)
+  WHERE user_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS jobs_user_kind_created_idx;
+
+ALTER TABLE jobs
+  DROP COLUMN result,
+  DROP COLUMN user_id;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Guids of articles deleted by retention, so a feed still carrying them doesn't
+-- insert them again on the next fetch. Rows go away with their feed.
+CREATE TABLE IF NOT EXISTS pruned_articles (
+  feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+  guid TEXT NOT NULL,
+  pruned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (feed_id, guid)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS pruned_articles;
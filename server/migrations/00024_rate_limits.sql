@@ -0,0 +1,14 @@
+-- +goose Up
+-- Recent events per rate limit key, shared by every API instance. Rows expire
+-- once their newest event left the window.
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key TEXT PRIMARY KEY,
+  events TIMESTAMP[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_expires_at_idx ON rate_limits (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS rate_limits_expires_at_idx;
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- A new email address waiting for verification. It replaces email only once
+-- the link sent to it is followed, until then the account keeps its old address.
+ALTER TABLE users
+  ADD COLUMN pending_email TEXT;
+
+-- +goose Down
+ALTER TABLE users
+  DROP COLUMN pending_email;